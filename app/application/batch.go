@@ -0,0 +1,85 @@
+package application
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure/repository"
+
+	"github.com/pkg/errors"
+)
+
+const defaultBatchChunkSize = 100
+
+// ErrBatchTooLarge is returned when a batch holds more items than the configured maximum.
+var ErrBatchTooLarge = errors.New("[Service][LinkIdentity] too many items in batch")
+
+// BatchItemResult is the outcome of one item of a batch. Exactly one of Result and Err is set.
+type BatchItemResult struct {
+	Result *IdentifyResult
+	Err    error
+}
+
+// IdentifyBatch identifies every request in order, as if Identify was called once per request. The requests are
+// processed in chunks, each in a single transaction, and every request in a savepoint of its own, so a failing
+// request is reported in its result without affecting the others. A chunk that cannot be committed at all fails
+// each of its requests.
+func (s *service) IdentifyBatch(ctx context.Context, reqs []IdentifyRequest) ([]BatchItemResult, error) {
+	if s.cfg.MaxBatchSize > 0 && len(reqs) > s.cfg.MaxBatchSize {
+		return nil, errors.WithMessagef(ErrBatchTooLarge, "%d items, at most %d are allowed", len(reqs), s.cfg.MaxBatchSize)
+	}
+
+	results := make([]BatchItemResult, len(reqs))
+	identifiers := make([][]*domain.Identifier, len(reqs))
+	observations := make([]*Observation, len(reqs))
+	for i, req := range reqs {
+		identifiers[i], results[i].Err = s.normalizeIdentifiers(req.Identifiers)
+		if results[i].Err == nil {
+			observations[i], results[i].Err = normalizeObservation(req.Observation)
+		}
+		if results[i].Err != nil {
+			identifiers[i] = nil
+		}
+	}
+
+	chunkSize := s.cfg.BatchChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultBatchChunkSize
+	}
+	for start := 0; start < len(reqs); start += chunkSize {
+		end := min(start+chunkSize, len(reqs))
+
+		// the chunk holds the locks of every identifier of its requests, taken before its transaction begins.
+		var keys []domain.IdentifierKey
+		for i := start; i < end; i++ {
+			keys = append(keys, identifierKeys(identifiers[i])...)
+		}
+		err := s.inLockedTransaction(ctx, keys, func(ctx context.Context) error {
+			for i := start; i < end; i++ {
+				if identifiers[i] == nil {
+					continue
+				}
+				var result *IdentifyResult
+				err := s.repo.WithSavepoint(ctx, func(ctx context.Context) error {
+					var err error
+					result, err = s.identify(ctx, identifiers[i], observations[i], reqs[i].CreatedAt, reqs[i].DryRun)
+					return err
+				})
+				// a conflict with a concurrent transaction aborts the whole chunk, which is then run again.
+				if errors.Is(err, repository.ErrSerializationFailure) {
+					return err
+				}
+				results[i] = BatchItemResult{Result: result, Err: err}
+			}
+			return nil
+		})
+		if err != nil {
+			for i := start; i < end; i++ {
+				if identifiers[i] != nil {
+					results[i] = BatchItemResult{Err: err}
+				}
+			}
+		}
+	}
+	return results, nil
+}
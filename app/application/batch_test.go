@@ -0,0 +1,67 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_IdentifyBatch ...
+func TestService_IdentifyBatch(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	dbErr := errors.New("connection reset")
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Twice()
+	repoMock.On("WithSavepoint", ctx).Return(nil).Twice()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(emailKey("doc@hillvalley.edu"))).
+		Return([]*domain.Contact{}, nil).Once()
+	repoMock.On("CreateContact", ctx, mock.Anything).
+		Return(newContact(1, "doc@hillvalley.edu", "", 0, t0), nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(emailKey("marty@hillvalley.edu"))).
+		Return([]*domain.Contact(nil), dbErr).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{BatchChunkSize: 2})
+	results, err := service.IdentifyBatch(ctx, []application.IdentifyRequest{
+		identifyRequest("doc@hillvalley.edu", ""),
+		identifyRequest("", "12"),
+		identifyRequest("marty@hillvalley.edu", ""),
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.True(t, results[0].Result.Created)
+	assert.ErrorIs(t, results[1].Err, application.ErrInvalidIdentifier)
+	assert.ErrorIs(t, results[2].Err, dbErr)
+	assert.Nil(t, results[2].Result)
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_IdentifyBatch_TooLarge ...
+func TestService_IdentifyBatch_TooLarge(t *testing.T) {
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+
+	service := application.NewService(repoMock, config.IdentityConfig{MaxBatchSize: 1})
+	_, err := service.IdentifyBatch(context.Background(), []application.IdentifyRequest{
+		identifyRequest("doc@hillvalley.edu", ""),
+		identifyRequest("marty@hillvalley.edu", ""),
+	})
+
+	assert.ErrorIs(t, err, application.ErrBatchTooLarge)
+	repoMock.AssertExpectations(t)
+}
@@ -0,0 +1,265 @@
+package application
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidDenylistEntry is returned when a denylist entry has no value, an unknown match or an invalid
+	// regular expression.
+	ErrInvalidDenylistEntry = errors.New("[Service][LinkIdentity] invalid denylist entry")
+	// ErrDenylistEntryNotFound is returned when a denylist entry a request refers to does not exist.
+	ErrDenylistEntryNotFound = errors.New("[Service][LinkIdentity] denylist entry not found")
+)
+
+// DenylistRequest ...
+type DenylistRequest struct {
+	// Type must be a registered identifier type.
+	Type string
+	// Value is an identifier value, normalized like the identifiers of Identify, for DenylistMatchExact, or a
+	// regular expression matched against normalized values for DenylistMatchRegex. Regular expressions match
+	// anywhere in the value unless they are anchored with ^ and $.
+	Value string
+	// Match is domain.DenylistMatchExact or domain.DenylistMatchRegex. It defaults to exact.
+	Match  string
+	Reason string
+}
+
+// denylistMatcher is a denylist entry ready to be matched.
+type denylistMatcher struct {
+	entry *domain.DenylistEntry
+	re    *regexp.Regexp
+}
+
+func (m denylistMatcher) matches(key domain.IdentifierKey) bool {
+	if m.entry.Type != key.Type {
+		return false
+	}
+	if m.re != nil {
+		return m.re.MatchString(key.Value)
+	}
+	return m.entry.Value == key.Value
+}
+
+// denylistCache holds the compiled denylist of every tenant, so that identify does not load and compile it on
+// every call. The service drops the denylist of a tenant when it changes one of its entries. Denylists also expire
+// after ttl, which bounds how long a change made through another instance goes unseen; a ttl that is not positive
+// never expires them.
+type denylistCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	tenants map[string]*cachedDenylist
+}
+
+type cachedDenylist struct {
+	matchers []denylistMatcher
+	loaded   bool
+	loadedAt time.Time
+	// generation counts the invalidations, so that a denylist loaded before one is not stored after it.
+	generation uint64
+}
+
+func newDenylistCache(ttl time.Duration) *denylistCache {
+	return &denylistCache{ttl: ttl, tenants: make(map[string]*cachedDenylist)}
+}
+
+// get returns the denylist of the tenant if it is cached and fresh. Otherwise it returns the generation to store
+// the denylist loaded next with.
+func (c *denylistCache) get(tenantID string, now time.Time) ([]denylistMatcher, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.tenants[tenantID]
+	if !ok {
+		return nil, false, 0
+	}
+	if !cached.loaded || (c.ttl > 0 && now.Sub(cached.loadedAt) >= c.ttl) {
+		return nil, false, cached.generation
+	}
+	return cached.matchers, true, cached.generation
+}
+
+// put stores the denylist of the tenant unless it was invalidated since generation was returned by get.
+func (c *denylistCache) put(tenantID string, generation uint64, matchers []denylistMatcher, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.tenants[tenantID]
+	if !ok {
+		cached = &cachedDenylist{}
+		c.tenants[tenantID] = cached
+	}
+	if cached.generation != generation {
+		return
+	}
+	cached.matchers = matchers
+	cached.loaded = true
+	cached.loadedAt = now
+}
+
+// invalidate drops the denylist of the tenant.
+func (c *denylistCache) invalidate(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.tenants[tenantID]
+	if !ok {
+		cached = &cachedDenylist{}
+		c.tenants[tenantID] = cached
+	}
+	cached.matchers = nil
+	cached.loaded = false
+	cached.generation++
+}
+
+// DenylistEntries returns every denylist entry in id order.
+func (s *service) DenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error) {
+	entries, err := s.repo.ListDenylistEntries(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while listing denylist entries")
+	}
+	return entries, nil
+}
+
+// CreateDenylistEntry adds an entry to the denylist. From then on, the identifiers it matches no longer link
+// contacts; contacts linked through them before stay linked until they are split.
+func (s *service) CreateDenylistEntry(ctx context.Context, req DenylistRequest) (*domain.DenylistEntry, error) {
+	entry := &domain.DenylistEntry{}
+	if err := s.fillDenylistEntry(entry, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateDenylistEntry(ctx, entry); err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while creating denylist entry")
+	}
+	s.denylists.invalidate(infrastructure.TenantID(ctx))
+	return entry, nil
+}
+
+// UpdateDenylistEntry replaces the denylist entry with the given id.
+func (s *service) UpdateDenylistEntry(
+	ctx context.Context,
+	id uint,
+	req DenylistRequest,
+) (*domain.DenylistEntry, error) {
+	entry, err := s.repo.GetDenylistEntry(ctx, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting denylist entry")
+	}
+	if entry == nil {
+		return nil, errors.WithMessagef(ErrDenylistEntryNotFound, "entry %d", id)
+	}
+	if err := s.fillDenylistEntry(entry, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateDenylistEntry(ctx, entry); err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while updating denylist entry")
+	}
+	s.denylists.invalidate(infrastructure.TenantID(ctx))
+	return entry, nil
+}
+
+// DeleteDenylistEntry removes the denylist entry with the given id.
+func (s *service) DeleteDenylistEntry(ctx context.Context, id uint) error {
+	deleted, err := s.repo.DeleteDenylistEntry(ctx, id)
+	if err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while deleting denylist entry")
+	}
+	if !deleted {
+		return errors.WithMessagef(ErrDenylistEntryNotFound, "entry %d", id)
+	}
+	s.denylists.invalidate(infrastructure.TenantID(ctx))
+	return nil
+}
+
+// fillDenylistEntry validates the request and copies it into the entry, normalizing exact values.
+func (s *service) fillDenylistEntry(entry *domain.DenylistEntry, req DenylistRequest) error {
+	if req.Match == "" {
+		req.Match = domain.DenylistMatchExact
+	}
+	if !s.identifiers.Has(req.Type) {
+		return errors.Wrapf(ErrUnknownIdentifierType, "%q", req.Type)
+	}
+
+	value := strings.TrimSpace(req.Value)
+	switch req.Match {
+	case domain.DenylistMatchExact:
+		identifier, err := s.identifiers.Normalize(req.Type, value)
+		if err != nil {
+			return err
+		}
+		value = identifier.Value
+	case domain.DenylistMatchRegex:
+		if value == "" {
+			return errors.WithMessage(ErrInvalidDenylistEntry, "empty pattern")
+		}
+		if _, err := regexp.Compile(value); err != nil {
+			return errors.WithMessagef(ErrInvalidDenylistEntry, "pattern %q: %s", value, err)
+		}
+	default:
+		return errors.WithMessagef(ErrInvalidDenylistEntry, "unknown match %q", req.Match)
+	}
+
+	entry.Type = req.Type
+	entry.Match = req.Match
+	entry.Value = value
+	entry.Reason = strings.TrimSpace(req.Reason)
+	return nil
+}
+
+// denylistMatchers returns the denylist of the tenant of ctx to match identifiers against, from the cache when it
+// holds it.
+func (s *service) denylistMatchers(ctx context.Context) ([]denylistMatcher, error) {
+	tenantID := infrastructure.TenantID(ctx)
+	matchers, ok, generation := s.denylists.get(tenantID, time.Now())
+	if ok {
+		return matchers, nil
+	}
+	matchers, err := s.loadDenylist(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.denylists.put(tenantID, generation, matchers, time.Now())
+	return matchers, nil
+}
+
+// loadDenylist loads and compiles the denylist.
+func (s *service) loadDenylist(ctx context.Context) ([]denylistMatcher, error) {
+	entries, err := s.repo.ListDenylistEntries(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while listing denylist entries")
+	}
+
+	matchers := make([]denylistMatcher, 0, len(entries))
+	for _, e := range entries {
+		m := denylistMatcher{entry: e}
+		if e.Match == domain.DenylistMatchRegex {
+			if m.re, err = regexp.Compile(e.Value); err != nil {
+				return nil, errors.Wrapf(err, "[Service][LinkIdentity] invalid pattern of denylist entry %d", e.EntryID)
+			}
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// dropDenylisted returns the keys no denylist entry matches and records the others in g.
+func dropDenylisted(keys []domain.IdentifierKey, g *guarded) []domain.IdentifierKey {
+	if len(g.denylist) == 0 {
+		return keys
+	}
+	var allowed []domain.IdentifierKey
+	for _, key := range keys {
+		if g.denylisted(key) {
+			g.keys[key] = true
+			continue
+		}
+		allowed = append(allowed, key)
+	}
+	return allowed
+}
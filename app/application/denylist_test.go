@@ -0,0 +1,183 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Identify_Denylist ...
+func TestService_Identify_Denylist(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	storePhone := phoneKey("+4917600000000")
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("ListDenylistEntries", ctx).Return([]*domain.DenylistEntry{
+		{EntryID: 1, Type: domain.IdentifierTypePhone, Match: domain.DenylistMatchExact, Value: storePhone.Value},
+		{EntryID: 2, Type: domain.IdentifierTypeEmail, Match: domain.DenylistMatchRegex, Value: `^noreply@`},
+	}, nil).Once()
+	// the store phone is not looked up, so the contacts already carrying it are not linked.
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"))).
+		Return([]*domain.Contact{}, nil).Once()
+	repoMock.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.LinkedPrecedence == "primary" && c.LinkedID == 0 && c.HasIdentifier(storePhone)
+	})).Return(newContact(5, "george@hillvalley.edu", storePhone.Value, 0, t0), nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	result, err := service.Identify(ctx, application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+		{Type: domain.IdentifierTypeEmail, Value: "george@hillvalley.edu"},
+		{Type: domain.IdentifierTypePhone, Value: "+49 176 0000 0000"},
+		{Type: domain.IdentifierTypeEmail, Value: "noreply@hillvalley.edu"},
+	}})
+
+	assert.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, uint(5), result.Contacts[0].ContactID)
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_Identify_DenylistCache ...
+func TestService_Identify_DenylistCache(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	shopA := infrastructure.WithTenantID(context.Background(), "shop-a")
+	shopB := infrastructure.WithTenantID(context.Background(), "shop-b")
+	c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+	entries := []*domain.DenylistEntry{
+		{EntryID: 1, Type: domain.IdentifierTypeEmail, Match: domain.DenylistMatchRegex, Value: `^noreply@`},
+	}
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("WithLockedTransaction", mock.Anything, mock.Anything).Return(nil)
+	repoMock.On("GetContactsByIdentifiers", mock.Anything, keys(emailKey("george@hillvalley.edu"))).
+		Return([]*domain.Contact{c1}, nil)
+	repoMock.On("GetContactsByLinkedIDs", mock.Anything, []uint{1}).Return([]*domain.Contact{c1}, nil)
+	repoMock.On("GetAllSecondaryContacts", mock.Anything, uint(1)).Return([]*domain.Contact{c1}, nil)
+	// the denylist of shop-a is loaded once, and again after one of its entries changed.
+	repoMock.On("ListDenylistEntries", shopA).Return(entries, nil).Twice()
+	repoMock.On("ListDenylistEntries", shopB).Return([]*domain.DenylistEntry{}, nil).Once()
+	repoMock.On("CreateDenylistEntry", shopA, mock.Anything).Return(nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	identify := func(ctx context.Context) {
+		_, err := service.Identify(ctx, identifyRequest("george@hillvalley.edu", ""))
+		assert.NoError(t, err)
+	}
+	identify(shopA)
+	identify(shopA)
+	identify(shopB)
+	_, err := service.CreateDenylistEntry(shopA, application.DenylistRequest{
+		Type: domain.IdentifierTypeEmail, Match: domain.DenylistMatchRegex, Value: `^info@`,
+	})
+	assert.NoError(t, err)
+	identify(shopA)
+	identify(shopB)
+
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_CreateDenylistEntry ...
+func TestService_CreateDenylistEntry(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Request       application.DenylistRequest
+		ExpectedEntry *domain.DenylistEntry
+		ExpectedError error
+	}{
+		{
+			Name:    "Exact values are normalized",
+			Request: application.DenylistRequest{Type: "phone", Value: "+49 176 0000 0000", Reason: " store phone "},
+			ExpectedEntry: &domain.DenylistEntry{
+				Type: "phone", Match: domain.DenylistMatchExact, Value: "+4917600000000", Reason: "store phone",
+			},
+		},
+		{
+			Name:    "Patterns are kept as they are",
+			Request: application.DenylistRequest{Type: "email", Value: `^(a|test)@a\.com$`, Match: "regex"},
+			ExpectedEntry: &domain.DenylistEntry{
+				Type: "email", Match: domain.DenylistMatchRegex, Value: `^(a|test)@a\.com$`,
+			},
+		},
+		{
+			Name:          "Invalid pattern",
+			Request:       application.DenylistRequest{Type: "email", Value: `^(a@a\.com$`, Match: "regex"},
+			ExpectedError: application.ErrInvalidDenylistEntry,
+		},
+		{
+			Name:          "Unknown match",
+			Request:       application.DenylistRequest{Type: "email", Value: "a@a.com", Match: "prefix"},
+			ExpectedError: application.ErrInvalidDenylistEntry,
+		},
+		{
+			Name:          "Invalid exact value",
+			Request:       application.DenylistRequest{Type: "phone", Value: "12"},
+			ExpectedError: application.ErrInvalidIdentifier,
+		},
+		{
+			Name:          "Unknown identifier type",
+			Request:       application.DenylistRequest{Type: "fax", Value: "12"},
+			ExpectedError: application.ErrUnknownIdentifierType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.ExpectedEntry != nil {
+				repoMock.On("CreateDenylistEntry", ctx, tt.ExpectedEntry).Return(nil).Once()
+			}
+
+			service := application.NewService(repoMock, config.IdentityConfig{DefaultPhoneRegion: "DE"})
+			entry, err := service.CreateDenylistEntry(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedEntry, entry)
+		})
+	}
+}
+
+// TestService_UpdateDenylistEntry ...
+func TestService_UpdateDenylistEntry(t *testing.T) {
+	ctx := context.Background()
+	req := application.DenylistRequest{Type: "email", Value: "A@A.com"}
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("GetDenylistEntry", ctx, uint(1)).
+		Return(&domain.DenylistEntry{EntryID: 1, Type: "phone", Match: "exact", Value: "+4917600000000"}, nil).Once()
+	repoMock.On("UpdateDenylistEntry", ctx, &domain.DenylistEntry{
+		EntryID: 1, Type: "email", Match: "exact", Value: "a@a.com",
+	}).Return(nil).Once()
+	repoMock.On("GetDenylistEntry", ctx, uint(2)).Return((*domain.DenylistEntry)(nil), nil).Once()
+	repoMock.On("DeleteDenylistEntry", ctx, uint(1)).Return(true, nil).Once()
+	repoMock.On("DeleteDenylistEntry", ctx, uint(2)).Return(false, nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	entry, err := service.UpdateDenylistEntry(ctx, 1, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "a@a.com", entry.Value)
+
+	_, err = service.UpdateDenylistEntry(ctx, 2, req)
+	assert.ErrorIs(t, err, application.ErrDenylistEntryNotFound)
+
+	assert.NoError(t, service.DeleteDenylistEntry(ctx, 1))
+	assert.ErrorIs(t, service.DeleteDenylistEntry(ctx, 2), application.ErrDenylistEntryNotFound)
+	repoMock.AssertExpectations(t)
+}
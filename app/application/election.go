@@ -0,0 +1,125 @@
+package application
+
+import (
+	"time"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the election policies the configuration can select.
+const (
+	ElectionPolicyOldest           = "oldest"
+	ElectionPolicyLoyaltyID        = "loyalty_id"
+	ElectionPolicyRecentlyVerified = "recently_verified"
+)
+
+// ErrUnknownElectionPolicy is returned for an election policy name that is not known.
+var ErrUnknownElectionPolicy = errors.New("[Service][LinkIdentity] unknown election policy")
+
+// ElectionPolicy decides which contact of a cluster is its primary. Every other contact of the cluster is linked
+// to the contact it elects. Elect must be deterministic: electing the same cluster again, in any order, has to
+// return the same contact, or clusters would be relinked back and forth.
+type ElectionPolicy interface {
+	// Elect returns the primary of the cluster, which is never empty. The cluster may hold a contact that is not
+	// stored yet, without a ContactID; it can be elected like any other contact.
+	Elect(cluster []*domain.Contact) *domain.Contact
+}
+
+// NewElectionPolicy returns the election policy with the given name. An empty name selects ElectionPolicyOldest.
+func NewElectionPolicy(name string) (ElectionPolicy, error) {
+	switch name {
+	case "", ElectionPolicyOldest:
+		return OldestFirst(), nil
+	case ElectionPolicyLoyaltyID:
+		return IdentifierFirst(domain.IdentifierTypeLoyaltyID), nil
+	case ElectionPolicyRecentlyVerified:
+		return RecentlyVerifiedFirst(), nil
+	default:
+		return nil, errors.WithMessagef(ErrUnknownElectionPolicy, "%q", name)
+	}
+}
+
+type oldestFirst struct{}
+
+// OldestFirst returns the default election policy: the oldest primary of the cluster stays primary, so merging
+// clusters demotes the younger primaries. A cluster without any primary elects its oldest contact.
+func OldestFirst() ElectionPolicy {
+	return oldestFirst{}
+}
+
+func (oldestFirst) Elect(cluster []*domain.Contact) *domain.Contact {
+	return electPrimary(cluster)
+}
+
+type identifierFirst struct {
+	typ string
+}
+
+// IdentifierFirst returns an election policy that elects the oldest contact carrying an identifier of the type,
+// for instance the contact that joined the loyalty program. Clusters without such a contact elect like
+// OldestFirst.
+func IdentifierFirst(typ string) ElectionPolicy {
+	return identifierFirst{typ: typ}
+}
+
+func (p identifierFirst) Elect(cluster []*domain.Contact) *domain.Contact {
+	var elected *domain.Contact
+	for _, c := range cluster {
+		if hasIdentifierType(c, p.typ) && (elected == nil || isOlder(c, elected)) {
+			elected = c
+		}
+	}
+	if elected == nil {
+		return electPrimary(cluster)
+	}
+	return elected
+}
+
+type recentlyVerifiedFirst struct{}
+
+// RecentlyVerifiedFirst returns an election policy that elects the contact with the most recently verified
+// identifier, the one the customer last proved to own. Contacts verified at the same time are ranked like
+// OldestFirst, and clusters without a verified identifier elect like OldestFirst.
+func RecentlyVerifiedFirst() ElectionPolicy {
+	return recentlyVerifiedFirst{}
+}
+
+func (recentlyVerifiedFirst) Elect(cluster []*domain.Contact) *domain.Contact {
+	var elected *domain.Contact
+	var electedAt time.Time
+	for _, c := range cluster {
+		verifiedAt := lastVerifiedAt(c)
+		if verifiedAt.IsZero() {
+			continue
+		}
+		if elected == nil || verifiedAt.After(electedAt) || (verifiedAt.Equal(electedAt) && isOlder(c, elected)) {
+			elected, electedAt = c, verifiedAt
+		}
+	}
+	if elected == nil {
+		return electPrimary(cluster)
+	}
+	return elected
+}
+
+// lastVerifiedAt returns when an identifier of the contact was last verified, or the zero time.
+func lastVerifiedAt(c *domain.Contact) time.Time {
+	var last time.Time
+	for _, i := range c.Identifiers {
+		if i.VerifiedAt != nil && i.VerifiedAt.After(last) {
+			last = *i.VerifiedAt
+		}
+	}
+	return last
+}
+
+func hasIdentifierType(c *domain.Contact, typ string) bool {
+	for _, i := range c.Identifiers {
+		if i.Type == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestElectionPolicy ...
+func TestElectionPolicy(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	loyal := func(c *domain.Contact) *domain.Contact {
+		c.Identifiers = append(c.Identifiers, &domain.Identifier{
+			ContactID: c.ContactID, Type: domain.IdentifierTypeLoyaltyID, Value: "FK-1985", RawValue: "fk-1985",
+		})
+		return c
+	}
+	verified := func(c *domain.Contact, at time.Time) *domain.Contact {
+		c.Identifiers[0].VerifiedAt = &at
+		return c
+	}
+
+	tests := []struct {
+		Name     string
+		Policy   string
+		Cluster  []*domain.Contact
+		Expected uint
+	}{
+		{
+			Name:   "Oldest primary wins",
+			Policy: application.ElectionPolicyOldest,
+			Cluster: []*domain.Contact{
+				newContact(2, "marty@hillvalley.edu", "", 0, t0.Add(time.Hour)),
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+			},
+			Expected: 1,
+		},
+		{
+			Name:   "Equal CreatedAt is broken by the lowest contact id",
+			Policy: "",
+			Cluster: []*domain.Contact{
+				newContact(3, "marty@hillvalley.edu", "", 0, t0),
+				newContact(2, "george@hillvalley.edu", "", 0, t0),
+			},
+			Expected: 2,
+		},
+		{
+			Name:   "A contact not stored yet loses a tie",
+			Policy: application.ElectionPolicyOldest,
+			Cluster: []*domain.Contact{
+				newContact(0, "marty@hillvalley.edu", "", 0, t0),
+				newContact(7, "george@hillvalley.edu", "", 0, t0),
+			},
+			Expected: 7,
+		},
+		{
+			Name:   "Contact with a loyalty id wins over an older primary",
+			Policy: application.ElectionPolicyLoyaltyID,
+			Cluster: []*domain.Contact{
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+				loyal(newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))),
+			},
+			Expected: 2,
+		},
+		{
+			Name:   "Oldest of several contacts with a loyalty id wins",
+			Policy: application.ElectionPolicyLoyaltyID,
+			Cluster: []*domain.Contact{
+				loyal(newContact(3, "", "+4917633333333", 1, t0.Add(2*time.Hour))),
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+				loyal(newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))),
+			},
+			Expected: 2,
+		},
+		{
+			Name:   "Cluster without a loyalty id elects the oldest primary",
+			Policy: application.ElectionPolicyLoyaltyID,
+			Cluster: []*domain.Contact{
+				newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour)),
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+			},
+			Expected: 1,
+		},
+		{
+			Name:   "Most recently verified contact wins over an older primary",
+			Policy: application.ElectionPolicyRecentlyVerified,
+			Cluster: []*domain.Contact{
+				verified(newContact(1, "george@hillvalley.edu", "", 0, t0), t0.Add(time.Hour)),
+				verified(newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour)), t0.Add(3*time.Hour)),
+				newContact(3, "", "+4917633333333", 1, t0.Add(2*time.Hour)),
+			},
+			Expected: 2,
+		},
+		{
+			Name:   "Contacts verified at the same time are ranked by age",
+			Policy: application.ElectionPolicyRecentlyVerified,
+			Cluster: []*domain.Contact{
+				verified(newContact(3, "", "+4917633333333", 1, t0.Add(2*time.Hour)), t0.Add(3*time.Hour)),
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+				verified(newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour)), t0.Add(3*time.Hour)),
+			},
+			Expected: 2,
+		},
+		{
+			Name:   "Cluster without a verified identifier elects the oldest primary",
+			Policy: application.ElectionPolicyRecentlyVerified,
+			Cluster: []*domain.Contact{
+				newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour)),
+				newContact(1, "george@hillvalley.edu", "", 0, t0),
+			},
+			Expected: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			policy, err := application.NewElectionPolicy(tt.Policy)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.Expected, policy.Elect(tt.Cluster).ContactID)
+		})
+	}
+
+	_, err := application.NewElectionPolicy("newest")
+	assert.ErrorIs(t, err, application.ErrUnknownElectionPolicy)
+}
+
+// TestService_Identify_ElectionPolicy ...
+func TestService_Identify_ElectionPolicy(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+	loyaltyKey := domain.IdentifierKey{Type: domain.IdentifierTypeLoyaltyID, Value: "FK-1985"}
+	c2 := newContact(2, "george@hillvalley.edu", "", 0, t0.Add(time.Hour))
+	c2.Identifiers = append(c2.Identifiers, &domain.Identifier{
+		ContactID: 2, Type: loyaltyKey.Type, Value: loyaltyKey.Value, RawValue: "fk-1985",
+	})
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"), loyaltyKey)).
+		Return([]*domain.Contact{c1}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+	repoMock.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.LinkedPrecedence == "primary" && c.LinkedID == 0
+	})).Return(c2, nil).Once()
+	repoMock.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.ContactID == 1 && c.LinkedID == 2 && c.LinkedPrecedence == "secondary"
+	})).Return(&domain.Contact{}, nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetAllSecondaryContacts", ctx, uint(2)).Return([]*domain.Contact{c2, c1}, nil).Once()
+
+	service := application.NewService(
+		repoMock,
+		config.IdentityConfig{},
+		application.WithElectionPolicy(application.IdentifierFirst(domain.IdentifierTypeLoyaltyID)),
+	)
+	result, err := service.Identify(ctx, application.IdentifyRequest{
+		Identifiers: []application.IdentifierValue{
+			{Type: domain.IdentifierTypeEmail, Value: "george@hillvalley.edu"},
+			{Type: domain.IdentifierTypeLoyaltyID, Value: "fk-1985"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, uint(2), result.Contacts[0].ContactID)
+	repoMock.AssertExpectations(t)
+}
@@ -0,0 +1,70 @@
+package application
+
+import (
+	"strings"
+)
+
+// EmailCanonicalizer turns an email address into the canonical form it is matched by. Addresses with the same
+// canonical form belong to the same person.
+type EmailCanonicalizer interface {
+	Canonicalize(email string) string
+}
+
+// EmailDomainRule describes how the mailbox provider of a domain delivers mail, so that addresses it treats as
+// one mailbox share a canonical form.
+type EmailDomainRule struct {
+	// StripPlusTags drops everything from the first "+" of the local part, as in "doc+fluxkart@gmail.com".
+	StripPlusTags bool
+	// RemoveDots drops the dots of the local part, as in "d.o.c@gmail.com".
+	RemoveDots bool
+}
+
+type emailCanonicalizer struct {
+	rules map[string]EmailDomainRule
+}
+
+// NewEmailCanonicalizer returns the default EmailCanonicalizer. It lower-cases every address and applies the
+// rule of the address domain, if there is one, to the local part.
+func NewEmailCanonicalizer(rules map[string]EmailDomainRule) EmailCanonicalizer {
+	lowered := make(map[string]EmailDomainRule, len(rules))
+	for domain, rule := range rules {
+		lowered[strings.ToLower(domain)] = rule
+	}
+	return &emailCanonicalizer{rules: lowered}
+}
+
+// newEmailDomainRules builds the rules of NewEmailCanonicalizer from the domain lists of the configuration.
+func newEmailDomainRules(plusTagDomains, dotInsensitiveDomains []string) map[string]EmailDomainRule {
+	rules := make(map[string]EmailDomainRule)
+	for _, domain := range plusTagDomains {
+		rule := rules[domain]
+		rule.StripPlusTags = true
+		rules[domain] = rule
+	}
+	for _, domain := range dotInsensitiveDomains {
+		rule := rules[domain]
+		rule.RemoveDots = true
+		rules[domain] = rule
+	}
+	return rules
+}
+
+func (c *emailCanonicalizer) Canonicalize(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+
+	local, domain := email[:at], email[at+1:]
+	rule := c.rules[domain]
+	if rule.StripPlusTags {
+		if plus := strings.Index(local, "+"); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+	if rule.RemoveDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
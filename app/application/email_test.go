@@ -0,0 +1,35 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/link-identity/app/application"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEmailCanonicalizer_Canonicalize ...
+func TestEmailCanonicalizer_Canonicalize(t *testing.T) {
+	canonicalizer := application.NewEmailCanonicalizer(map[string]application.EmailDomainRule{
+		"Gmail.com":     {StripPlusTags: true, RemoveDots: true},
+		"fastmail.com":  {StripPlusTags: true},
+		"hillvalley.ru": {RemoveDots: true},
+	})
+
+	tests := []struct {
+		Name     string
+		Email    string
+		Expected string
+	}{
+		{Name: "Lower-cases every address", Email: " Doc@HillValley.edu", Expected: "doc@hillvalley.edu"},
+		{Name: "Keeps tags and dots of other domains", Email: "d.oc+x@hillvalley.edu", Expected: "d.oc+x@hillvalley.edu"},
+		{Name: "Strips tags and dots of gmail", Email: "E.Brown+FluxKart@gmail.com", Expected: "ebrown@gmail.com"},
+		{Name: "Strips tags only", Email: "e.brown+fluxkart@fastmail.com", Expected: "e.brown@fastmail.com"},
+		{Name: "Removes dots only", Email: "e.brown+x@hillvalley.ru", Expected: "ebrown+x@hillvalley.ru"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, canonicalizer.Canonicalize(tt.Email))
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package application
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidErasureRequest is returned when an erasure request does not name exactly one identifier or contact,
+// who requested it, or a known mode.
+var ErrInvalidErasureRequest = errors.New(
+	"[Service][LinkIdentity] an erasure needs one identifier or contact, a mode and a requester",
+)
+
+// ErasureRequest ...
+type ErasureRequest struct {
+	// Identifier erases the identifier from every contact carrying it. Contacts left without any identifier are
+	// deleted; the other contacts of their cluster stay linked.
+	Identifier *IdentifierValue
+	// ContactID erases every contact of the cluster of the contact.
+	ContactID uint
+	// Mode is domain.ErasureModeSoft or domain.ErasureModeHard. It defaults to soft.
+	Mode        string
+	RequestedBy string
+}
+
+// Erase deletes the contacts of a customer who asked to be forgotten, or a single identifier of theirs. When the
+// primary of a cluster is deleted, the remaining contacts elect a new primary, the oldest one by default, and are
+// re-pointed to it. Hard erasures also scrub the erased identifiers from the link events. Every erasure leaves a
+// receipt that holds no personal data.
+func (s *service) Erase(ctx context.Context, req ErasureRequest) (*domain.ErasureReceipt, error) {
+	if req.Mode == "" {
+		req.Mode = domain.ErasureModeSoft
+	}
+	if (req.Identifier == nil) == (req.ContactID == 0) || strings.TrimSpace(req.RequestedBy) == "" ||
+		(req.Mode != domain.ErasureModeSoft && req.Mode != domain.ErasureModeHard) {
+		return nil, ErrInvalidErasureRequest
+	}
+
+	var identifier *domain.Identifier
+	if req.Identifier != nil {
+		var err error
+		identifier, err = s.identifiers.Normalize(req.Identifier.Type, req.Identifier.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the identifier to erase is known up front and locked like those of Identify; the identifiers of a cluster are
+	// only known once it is read, and the erasure of a cluster is kept apart from concurrent calls by the
+	// serializable isolation alone.
+	var keys []domain.IdentifierKey
+	if identifier != nil {
+		keys = append(keys, identifier.Key())
+	}
+	var receipt *domain.ErasureReceipt
+	err := s.inLockedTransaction(ctx, keys, func(ctx context.Context) error {
+		var err error
+		if identifier != nil {
+			receipt, err = s.eraseIdentifier(ctx, identifier.Key(), req)
+		} else {
+			receipt, err = s.eraseCluster(ctx, req)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.repo.CreateErasureReceipt(ctx, receipt); err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while recording erasure receipt")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func (s *service) eraseIdentifier(
+	ctx context.Context,
+	key domain.IdentifierKey,
+	req ErasureRequest,
+) (*domain.ErasureReceipt, error) {
+	matches, err := s.repo.GetContactsByIdentifiers(ctx, []domain.IdentifierKey{key})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by identifiers")
+	}
+	if len(matches) == 0 {
+		return nil, errors.WithMessagef(ErrContactNotFound, "no contact with the %s", key.Type)
+	}
+
+	hard := req.Mode == domain.ErasureModeHard
+	receipt := newErasureReceipt(ctx, req, key.Type)
+	receipt.SubjectHash = subjectHash(key)
+
+	var deleted, reelected []uint
+	seen := make(map[uint]bool)
+	for _, match := range matches {
+		if seen[match.ContactID] {
+			continue
+		}
+		cluster, err := s.linkedCluster(ctx, match.ContactID)
+		if err != nil {
+			return nil, err
+		}
+
+		previous := s.election.Elect(cluster)
+		var identifierIDs, contactIDs []uint
+		var erased, remaining []*domain.Contact
+		for _, c := range cluster {
+			seen[c.ContactID] = true
+			kept := make([]*domain.Identifier, 0, len(c.Identifiers))
+			for _, i := range c.Identifiers {
+				if i.Key() == key {
+					identifierIDs = append(identifierIDs, i.IdentifierID)
+					continue
+				}
+				kept = append(kept, i)
+			}
+			if len(kept) == 0 && len(c.Identifiers) > 0 {
+				contactIDs = append(contactIDs, c.ContactID)
+				erased = append(erased, c)
+				continue
+			}
+			// the erased identifier takes no part in the election of the new primary, as a verified or loyalty
+			// identifier would under other policies than the oldest first.
+			c.Identifiers = kept
+			remaining = append(remaining, c)
+		}
+
+		if err := s.repo.DeleteIdentifiers(ctx, identifierIDs, hard); err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while deleting identifiers")
+		}
+		if err := s.repo.DeleteContacts(ctx, contactIDs, hard); err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while deleting contacts")
+		}
+		if err := s.recordErasedContacts(ctx, erased); err != nil {
+			return nil, err
+		}
+		receipt.DeletedIdentifiers += len(identifierIDs)
+		deleted = append(deleted, contactIDs...)
+
+		id, err := s.reelect(ctx, previous, cluster, remaining)
+		if err != nil {
+			return nil, err
+		}
+		if id != 0 {
+			reelected = append(reelected, id)
+		}
+	}
+
+	if hard {
+		if err := s.repo.ScrubLinkEvents(ctx, []domain.IdentifierKey{key}); err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while scrubbing link events")
+		}
+	}
+	receipt.DeletedContactIDs = joinIDs(deleted)
+	receipt.ReelectedContactIDs = joinIDs(reelected)
+	return receipt, nil
+}
+
+func (s *service) eraseCluster(ctx context.Context, req ErasureRequest) (*domain.ErasureReceipt, error) {
+	cluster, err := s.linkedCluster(ctx, req.ContactID)
+	if err != nil {
+		return nil, err
+	}
+	var identifiers []*domain.Identifier
+	contactIDs := make([]uint, 0, len(cluster))
+	for _, c := range cluster {
+		identifiers = append(identifiers, c.Identifiers...)
+		contactIDs = append(contactIDs, c.ContactID)
+	}
+	keys := identifierKeys(identifiers)
+
+	hard := req.Mode == domain.ErasureModeHard
+	if err := s.repo.DeleteContacts(ctx, contactIDs, hard); err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while deleting contacts")
+	}
+	if err := s.recordErasedContacts(ctx, cluster); err != nil {
+		return nil, err
+	}
+	if hard {
+		if err := s.repo.ScrubLinkEvents(ctx, keys); err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while scrubbing link events")
+		}
+	}
+
+	receipt := newErasureReceipt(ctx, req, domain.ErasureScopeCluster)
+	receipt.ContactID = req.ContactID
+	receipt.DeletedContactIDs = joinIDs(contactIDs)
+	receipt.DeletedIdentifiers = len(identifiers)
+	return receipt, nil
+}
+
+// reelect relinks the contacts remaining in the cluster once the others were erased. previous is the primary the
+// cluster elected before the erasure. It returns the id of the contact elected primary in place of an erased one,
+// or 0 if the primary was kept.
+func (s *service) reelect(
+	ctx context.Context,
+	previous *domain.Contact,
+	cluster, remaining []*domain.Contact,
+) (uint, error) {
+	if len(remaining) == 0 || len(remaining) == len(cluster) {
+		return 0, nil
+	}
+	primary, err := s.mergeCluster(ctx, remaining, linkCause{action: domain.LinkActionErase})
+	if err != nil {
+		return 0, err
+	}
+	if primary.ContactID == previous.ContactID {
+		return 0, nil
+	}
+	return primary.ContactID, nil
+}
+
+// recordErasedContacts writes a DomainEventContactErased to the outbox for every contact erased.
+func (s *service) recordErasedContacts(ctx context.Context, contacts []*domain.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+	outbox, err := erasedEvents(ctx, contacts)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateOutboxEvents(ctx, outbox); err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while writing outbox events")
+	}
+	return nil
+}
+
+func newErasureReceipt(ctx context.Context, req ErasureRequest, scope string) *domain.ErasureReceipt {
+	return &domain.ErasureReceipt{
+		Mode:        req.Mode,
+		Scope:       scope,
+		RequestedBy: strings.TrimSpace(req.RequestedBy),
+		RequestID:   infrastructure.RequestID(ctx),
+		CreatedAt:   time.Now(),
+	}
+}
+
+// subjectHash returns the hex SHA-256 of the normalized identifier, so that a receipt can be matched against a
+// later request without storing the identifier itself.
+func subjectHash(key domain.IdentifierKey) string {
+	sum := sha256.Sum256([]byte(key.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func joinIDs(ids []uint) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.FormatUint(uint64(id), 10))
+	}
+	return strings.Join(s, ",")
+}
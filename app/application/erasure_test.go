@@ -0,0 +1,249 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Erase ...
+func TestService_Erase(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	// cluster returns a cluster of three contacts under contact 1. Contact 3 carries an email and a phone.
+	cluster := func() []*domain.Contact {
+		contacts := []*domain.Contact{
+			newContact(1, "george@hillvalley.edu", "", 0, t0),
+			newContact(2, "", "+4917611111111", 1, t0.Add(time.Hour)),
+			newContact(3, "biff@hillvalley.edu", "+4917633333333", 1, t0.Add(2*time.Hour)),
+		}
+		for _, c := range contacts {
+			for n, i := range c.Identifiers {
+				i.IdentifierID = c.ContactID*10 + uint(n) + 1
+			}
+		}
+		return contacts
+	}
+	email := func(value string) *application.IdentifierValue {
+		return &application.IdentifierValue{Type: domain.IdentifierTypeEmail, Value: value}
+	}
+
+	tests := []struct {
+		Name            string
+		Request         application.ErasureRequest
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedReceipt *domain.ErasureReceipt
+		ExpectedError   error
+	}{
+		{
+			Name: "Erasing the only identifier of the primary re-elects the oldest remaining contact",
+			Request: application.ErasureRequest{
+				Identifier: email("George@HillValley.edu"), RequestedBy: "dpo",
+			},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("WithLockedTransaction", ctx, []string{"email:george@hillvalley.edu"}).Return(nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, []domain.IdentifierKey{emailKey("george@hillvalley.edu")}).
+					Return(contacts[:1], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3}).Return(contacts[1:], nil).Once()
+				repo.On("DeleteIdentifiers", ctx, []uint{11}, false).Return(nil).Once()
+				repo.On("DeleteContacts", ctx, []uint{1}, false).Return(nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 2 && c.LinkedID == 0 && c.LinkedPrecedence == "primary"
+				})).Return(&domain.Contact{}, nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 3 && c.LinkedID == 2 && c.LinkedPrecedence == "secondary"
+				})).Return(&domain.Contact{}, nil).Once()
+				repo.On("CreateOutboxEvents", ctx, []*domain.OutboxEvent{
+					{
+						Type:      domain.DomainEventContactErased,
+						ContactID: 1,
+						Payload:   `{"contact_id":1,"primary_contact_id":1,"precedence":"primary","action":"erase"}`,
+					},
+				}).Return(nil).Once()
+				repo.On("CreateOutboxEvents", ctx, []*domain.OutboxEvent{
+					{
+						Type:      domain.DomainEventContactLinked,
+						ContactID: 2,
+						Payload: `{"contact_id":2,"primary_contact_id":2,"previous_primary_contact_id":1,` +
+							`"precedence":"primary","action":"erase"}`,
+					},
+					{
+						Type:      domain.DomainEventContactLinked,
+						ContactID: 3,
+						Payload: `{"contact_id":3,"primary_contact_id":2,"previous_primary_contact_id":1,` +
+							`"precedence":"secondary","action":"erase"}`,
+					},
+				}).Return(nil).Once()
+			},
+			ExpectedReceipt: &domain.ErasureReceipt{
+				Mode:                domain.ErasureModeSoft,
+				Scope:               domain.IdentifierTypeEmail,
+				DeletedContactIDs:   "1",
+				DeletedIdentifiers:  1,
+				ReelectedContactIDs: "2",
+				RequestedBy:         "dpo",
+			},
+		},
+		{
+			Name: "Hard erasure of one identifier keeps the contact and scrubs the link events",
+			Request: application.ErasureRequest{
+				Identifier: email("biff@hillvalley.edu"), Mode: domain.ErasureModeHard, RequestedBy: "dpo",
+			},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				key := emailKey("biff@hillvalley.edu")
+				repo.On("WithLockedTransaction", ctx, []string{key.String()}).Return(nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, []domain.IdentifierKey{key}).Return(contacts[2:], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return(contacts[2:], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return(contacts[1:2], nil).Once()
+				repo.On("DeleteIdentifiers", ctx, []uint{31}, true).Return(nil).Once()
+				repo.On("DeleteContacts", ctx, []uint(nil), true).Return(nil).Once()
+				repo.On("ScrubLinkEvents", ctx, []domain.IdentifierKey{key}).Return(nil).Once()
+			},
+			ExpectedReceipt: &domain.ErasureReceipt{
+				Mode:               domain.ErasureModeHard,
+				Scope:              domain.IdentifierTypeEmail,
+				DeletedIdentifiers: 1,
+				RequestedBy:        "dpo",
+			},
+		},
+		{
+			Name:    "Erasing a cluster deletes every contact",
+			Request: application.ErasureRequest{ContactID: 2, Mode: domain.ErasureModeHard, RequestedBy: "dpo"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("WithTransaction", ctx).Return(nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return(contacts[1:2], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return(contacts[2:], nil).Once()
+				repo.On("DeleteContacts", ctx, []uint{2, 1, 3}, true).Return(nil).Once()
+				repo.On("CreateOutboxEvents", ctx, mock.MatchedBy(func(events []*domain.OutboxEvent) bool {
+					return len(events) == 3 && events[0].Type == domain.DomainEventContactErased &&
+						events[0].ContactID == 2 && events[1].ContactID == 1 && events[2].ContactID == 3
+				})).Return(nil).Once()
+				repo.On("ScrubLinkEvents", ctx, mock.Anything).Return(nil).Once()
+			},
+			ExpectedReceipt: &domain.ErasureReceipt{
+				Mode:               domain.ErasureModeHard,
+				Scope:              domain.ErasureScopeCluster,
+				ContactID:          2,
+				DeletedContactIDs:  "2,1,3",
+				DeletedIdentifiers: 4,
+				RequestedBy:        "dpo",
+			},
+		},
+		{
+			Name:    "Unknown identifier",
+			Request: application.ErasureRequest{Identifier: email("marty@hillvalley.edu"), RequestedBy: "dpo"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, mock.Anything).Return([]*domain.Contact{}, nil).Once()
+			},
+			ExpectedError: application.ErrContactNotFound,
+		},
+		{
+			Name: "Identifier and contact",
+			Request: application.ErasureRequest{
+				Identifier: email("biff@hillvalley.edu"), ContactID: 3, RequestedBy: "dpo",
+			},
+			ExpectedError: application.ErrInvalidErasureRequest,
+		},
+		{
+			Name:          "Unknown mode",
+			Request:       application.ErasureRequest{ContactID: 3, Mode: "shred", RequestedBy: "dpo"},
+			ExpectedError: application.ErrInvalidErasureRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.Setup != nil {
+				tt.Setup(ctx, repoMock)
+				repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+				repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+				repoMock.On("CreateErasureReceipt", ctx, mock.Anything).Return(nil).Maybe()
+			}
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			receipt, err := service.Erase(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotZero(t, receipt.CreatedAt)
+			receipt.CreatedAt = time.Time{}
+			if tt.ExpectedReceipt.Scope != domain.ErasureScopeCluster {
+				assert.Len(t, receipt.SubjectHash, 64)
+				receipt.SubjectHash = ""
+			}
+			assert.Equal(t, tt.ExpectedReceipt, receipt)
+		})
+	}
+}
+
+// TestService_Erase_ReelectsWithoutErasedIdentifier ...
+func TestService_Erase_ReelectsWithoutErasedIdentifier(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	verified := func(c *domain.Contact, at time.Time) *domain.Contact {
+		for _, i := range c.Identifiers {
+			i.VerifiedAt = &at
+		}
+		return c
+	}
+	// contact 3 carries the erased email, verified last, and keeps its phone. Once the email is erased, contact 2
+	// is the most recently verified contact left.
+	c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+	c2 := verified(newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour)), t0.Add(24*time.Hour))
+	c3 := newContact(3, "george@hillvalley.edu", "+4917633333333", 1, t0.Add(2*time.Hour))
+	verifiedAt := t0.Add(48 * time.Hour)
+	c3.Identifiers[0].VerifiedAt = &verifiedAt
+	key := emailKey("george@hillvalley.edu")
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("WithLockedTransaction", ctx, []string{key.String()}).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, []domain.IdentifierKey{key}).
+		Return([]*domain.Contact{c1, c3}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1, c2, c3}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{2, 3}).Return([]*domain.Contact{c2, c3}, nil).Once()
+	repoMock.On("DeleteIdentifiers", ctx, mock.Anything, false).Return(nil).Once()
+	repoMock.On("DeleteContacts", ctx, []uint{1}, false).Return(nil).Once()
+	repoMock.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.ContactID == 2 && c.LinkedID == 0 && c.LinkedPrecedence == "primary"
+	})).Return(&domain.Contact{}, nil).Once()
+	repoMock.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.ContactID == 3 && c.LinkedID == 2 && c.LinkedPrecedence == "secondary"
+	})).Return(&domain.Contact{}, nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Twice()
+	repoMock.On("CreateErasureReceipt", ctx, mock.Anything).Return(nil).Once()
+
+	service := application.NewService(
+		repoMock, config.IdentityConfig{}, application.WithElectionPolicy(application.RecentlyVerifiedFirst()),
+	)
+	receipt, err := service.Erase(ctx, application.ErasureRequest{
+		Identifier:  &application.IdentifierValue{Type: domain.IdentifierTypeEmail, Value: "george@hillvalley.edu"},
+		RequestedBy: "dpo",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2", receipt.ReelectedContactIDs)
+	assert.Len(t, c3.Identifiers, 1)
+	assert.Equal(t, domain.IdentifierTypePhone, c3.Identifiers[0].Type)
+	repoMock.AssertExpectations(t)
+}
@@ -0,0 +1,208 @@
+package application
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+// Export formats.
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+const exportPageSize = 500
+
+// ErrUnknownExportFormat is returned for export formats other than ExportFormatNDJSON and ExportFormatCSV.
+var ErrUnknownExportFormat = errors.New("[Service][LinkIdentity] unknown export format")
+
+// ClusterRecord is the consolidated identity of a cluster. Values are listed as they were first received, those of
+// the primary first, and values with the same normalized form only once.
+type ClusterRecord struct {
+	PrimaryContactID    uint                `json:"primary_contact_id"`
+	Emails              []string            `json:"emails"`
+	PhoneNumbers        []string            `json:"phone_numbers"`
+	SecondaryContactIDs []uint              `json:"secondary_contact_ids"`
+	Identifiers         map[string][]string `json:"identifiers,omitempty"`
+}
+
+// NewClusterRecord consolidates the contacts of a cluster. Contacts without an id, as planned by a dry run, are
+// not listed as secondaries.
+func NewClusterRecord(contacts []*domain.Contact) *ClusterRecord {
+	var primary *domain.Contact
+	var secondaryIDs []uint
+	for _, c := range contacts {
+		if c.LinkedPrecedence == primaryPrecedence {
+			primary = c
+			continue
+		}
+		if c.ContactID != 0 {
+			secondaryIDs = append(secondaryIDs, c.ContactID)
+		}
+	}
+
+	ordered := contacts
+	record := &ClusterRecord{SecondaryContactIDs: secondaryIDs}
+	if primary != nil {
+		record.PrimaryContactID = primary.ContactID
+		ordered = append([]*domain.Contact{primary}, contacts...)
+	}
+
+	seen := make(map[domain.IdentifierKey]bool)
+	values := make(map[string][]string)
+	for _, c := range ordered {
+		for _, i := range c.Identifiers {
+			if seen[i.Key()] {
+				continue
+			}
+			seen[i.Key()] = true
+			values[i.Type] = append(values[i.Type], i.RawValue)
+		}
+	}
+
+	record.Emails = append([]string{}, values[domain.IdentifierTypeEmail]...)
+	record.PhoneNumbers = append([]string{}, values[domain.IdentifierTypePhone]...)
+	delete(values, domain.IdentifierTypeEmail)
+	delete(values, domain.IdentifierTypePhone)
+	if len(values) > 0 {
+		record.Identifiers = values
+	}
+	return record
+}
+
+// ExportClusters pages through the primaries in id order and hands the consolidated record of each cluster to fn.
+// It stops at the first error fn returns.
+func (s *service) ExportClusters(ctx context.Context, fn func(record *ClusterRecord) error) error {
+	var afterID uint
+	for {
+		primaries, err := s.repo.ListPrimaryContacts(ctx, afterID, exportPageSize)
+		if err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while listing primary contacts")
+		}
+		if len(primaries) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(primaries))
+		for _, p := range primaries {
+			ids = append(ids, p.ContactID)
+		}
+		contacts, err := s.repo.GetContactsByLinkedIDs(ctx, ids)
+		if err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting linked contacts")
+		}
+		clusters := make(map[uint][]*domain.Contact, len(primaries))
+		for _, c := range contacts {
+			clusters[rootID(c)] = append(clusters[rootID(c)], c)
+		}
+		for _, cluster := range clusters {
+			sort.Slice(cluster, func(i, j int) bool { return cluster[i].ContactID < cluster[j].ContactID })
+		}
+
+		for _, p := range primaries {
+			afterID = p.ContactID
+			if err := fn(NewClusterRecord(clusters[p.ContactID])); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ClusterWriter writes cluster records in an export format.
+type ClusterWriter interface {
+	Write(record *ClusterRecord) error
+	// Flush writes any buffered record to the underlying writer.
+	Flush() error
+}
+
+// NewClusterWriter returns a ClusterWriter for the format, ExportFormatNDJSON or ExportFormatCSV.
+func NewClusterWriter(w io.Writer, format string) (ClusterWriter, error) {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonClusterWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportFormatCSV:
+		return &csvClusterWriter{writer: csv.NewWriter(w)}, nil
+	default:
+		return nil, errors.WithMessagef(ErrUnknownExportFormat, "%q", format)
+	}
+}
+
+type ndjsonClusterWriter struct {
+	encoder *json.Encoder
+}
+
+// Write ...
+func (n *ndjsonClusterWriter) Write(record *ClusterRecord) error {
+	return n.encoder.Encode(record)
+}
+
+// Flush ...
+func (n *ndjsonClusterWriter) Flush() error {
+	return nil
+}
+
+// csvClusterWriter writes one row per cluster. Columns holding several values separate them with ";". The other
+// identifiers are written as "type:value" pairs.
+type csvClusterWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+// Write ...
+func (c *csvClusterWriter) Write(record *ClusterRecord) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	secondaryIDs := make([]string, 0, len(record.SecondaryContactIDs))
+	for _, id := range record.SecondaryContactIDs {
+		secondaryIDs = append(secondaryIDs, strconv.FormatUint(uint64(id), 10))
+	}
+	types := make([]string, 0, len(record.Identifiers))
+	for typ := range record.Identifiers {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	var identifiers []string
+	for _, typ := range types {
+		for _, value := range record.Identifiers[typ] {
+			identifiers = append(identifiers, typ+":"+value)
+		}
+	}
+
+	return c.writer.Write([]string{
+		strconv.FormatUint(uint64(record.PrimaryContactID), 10),
+		strings.Join(record.Emails, ";"),
+		strings.Join(record.PhoneNumbers, ";"),
+		strings.Join(secondaryIDs, ";"),
+		strings.Join(identifiers, ";"),
+	})
+}
+
+// Flush writes the header even when there was no record.
+func (c *csvClusterWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvClusterWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.writer.Write([]string{
+		"primary_contact_id", "emails", "phone_numbers", "secondary_contact_ids", "identifiers",
+	})
+}
@@ -0,0 +1,121 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_ExportClusters ...
+func TestService_ExportClusters(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	c1 := newContact(1, "George@HillValley.edu", "+4917611111111", 0, t0)
+	c2 := newContact(2, "george@hillvalley.edu", "+4917622222222", 1, t0.Add(time.Hour))
+	c3 := newContact(3, "marty@hillvalley.edu", "", 0, t0.Add(2*time.Hour))
+	c3.Identifiers = append(c3.Identifiers, &domain.Identifier{
+		ContactID: 3, Type: domain.IdentifierTypeLoyaltyID, Value: "FK-1985", RawValue: "fk-1985",
+	})
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListPrimaryContacts", ctx, uint(0), mock.Anything).Return([]*domain.Contact{c1, c3}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1, 3}).Return([]*domain.Contact{c2, c3, c1}, nil).Once()
+	repoMock.On("ListPrimaryContacts", ctx, uint(3), mock.Anything).Return([]*domain.Contact{}, nil).Once()
+
+	var records []*application.ClusterRecord
+	err := application.NewService(repoMock, config.IdentityConfig{}).
+		ExportClusters(ctx, func(record *application.ClusterRecord) error {
+			records = append(records, record)
+			return nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*application.ClusterRecord{
+		{
+			PrimaryContactID:    1,
+			Emails:              []string{"George@HillValley.edu"},
+			PhoneNumbers:        []string{"+4917611111111", "+4917622222222"},
+			SecondaryContactIDs: []uint{2},
+		},
+		{
+			PrimaryContactID: 3,
+			Emails:           []string{"marty@hillvalley.edu"},
+			PhoneNumbers:     []string{},
+			Identifiers:      map[string][]string{domain.IdentifierTypeLoyaltyID: {"fk-1985"}},
+		},
+	}, records)
+	repoMock.AssertExpectations(t)
+}
+
+// TestNewClusterWriter ...
+func TestNewClusterWriter(t *testing.T) {
+	records := []*application.ClusterRecord{
+		{
+			PrimaryContactID:    1,
+			Emails:              []string{"george@hillvalley.edu", "gmcfly@hillvalley.edu"},
+			PhoneNumbers:        []string{"+4917611111111"},
+			SecondaryContactIDs: []uint{2, 5},
+		},
+		{
+			PrimaryContactID: 3,
+			Emails:           []string{"marty@hillvalley.edu"},
+			PhoneNumbers:     []string{},
+			Identifiers:      map[string][]string{domain.IdentifierTypeLoyaltyID: {"fk-1985"}},
+		},
+	}
+
+	tests := []struct {
+		Name     string
+		Format   string
+		Records  []*application.ClusterRecord
+		Expected string
+	}{
+		{
+			Name:    "NDJSON",
+			Format:  application.ExportFormatNDJSON,
+			Records: records,
+			Expected: `{"primary_contact_id":1,"emails":["george@hillvalley.edu","gmcfly@hillvalley.edu"],` +
+				`"phone_numbers":["+4917611111111"],"secondary_contact_ids":[2,5]}` + "\n" +
+				`{"primary_contact_id":3,"emails":["marty@hillvalley.edu"],"phone_numbers":[],` +
+				`"secondary_contact_ids":null,"identifiers":{"loyalty_id":["fk-1985"]}}` + "\n",
+		},
+		{
+			Name:    "CSV",
+			Format:  application.ExportFormatCSV,
+			Records: records,
+			Expected: "primary_contact_id,emails,phone_numbers,secondary_contact_ids,identifiers\n" +
+				"1,george@hillvalley.edu;gmcfly@hillvalley.edu,+4917611111111,2;5,\n" +
+				"3,marty@hillvalley.edu,,,loyalty_id:fk-1985\n",
+		},
+		{
+			Name:     "CSV without clusters",
+			Format:   application.ExportFormatCSV,
+			Expected: "primary_contact_id,emails,phone_numbers,secondary_contact_ids,identifiers\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := application.NewClusterWriter(&out, tt.Format)
+			assert.NoError(t, err)
+
+			for _, r := range tt.Records {
+				assert.NoError(t, writer.Write(r))
+			}
+			assert.NoError(t, writer.Flush())
+			assert.Equal(t, tt.Expected, out.String())
+		})
+	}
+
+	_, err := application.NewClusterWriter(&bytes.Buffer{}, "parquet")
+	assert.ErrorIs(t, err, application.ErrUnknownExportFormat)
+}
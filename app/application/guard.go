@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// guarded collects the identifiers resolveCluster did not follow.
+type guarded struct {
+	// keys holds every identifier not followed: denylisted, flagged or carried by too many contacts.
+	keys map[domain.IdentifierKey]bool
+	// flag holds the identifiers found to be carried by too many contacts that are not flagged yet.
+	flag []*domain.FlaggedIdentifier
+	// denylist is loaded once per walk.
+	denylist []denylistMatcher
+}
+
+func (s *service) newGuarded(ctx context.Context) (*guarded, error) {
+	denylist, err := s.denylistMatchers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &guarded{keys: make(map[domain.IdentifierKey]bool), denylist: denylist}, nil
+}
+
+func (g *guarded) denylisted(key domain.IdentifierKey) bool {
+	for _, m := range g.denylist {
+		if m.matches(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardKeys drops the identifiers that resolveCluster must not follow and records them in g: the denylisted ones,
+// the flagged ones, and those carried by more contacts than identity.shared_identifier_threshold. It returns the
+// identifiers to follow.
+func (s *service) guardKeys(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+	g *guarded,
+) ([]domain.IdentifierKey, error) {
+	if len(keys) == 0 {
+		return keys, nil
+	}
+	keys = dropDenylisted(keys, g)
+	if s.cfg.SharedIdentifierThreshold <= 0 || len(keys) == 0 {
+		return keys, nil
+	}
+
+	flagged, err := s.repo.GetFlaggedIdentifiers(ctx, keys)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting flagged identifiers")
+	}
+	for _, f := range flagged {
+		g.keys[f.Key()] = true
+	}
+	var unflagged []domain.IdentifierKey
+	for _, key := range keys {
+		if !g.keys[key] {
+			unflagged = append(unflagged, key)
+		}
+	}
+	if len(unflagged) == 0 {
+		return nil, nil
+	}
+
+	counts, err := s.repo.CountContactsByIdentifiers(ctx, unflagged)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while counting contacts by identifiers")
+	}
+	var followed []domain.IdentifierKey
+	for _, key := range unflagged {
+		if counts[key] > s.cfg.SharedIdentifierThreshold {
+			g.keys[key] = true
+			g.flag = append(g.flag, &domain.FlaggedIdentifier{Type: key.Type, Value: key.Value, ContactCount: counts[key]})
+			continue
+		}
+		followed = append(followed, key)
+	}
+	return followed, nil
+}
+
+// guardedCluster returns the cluster of the oldest contact carrying exactly the identifiers, for a request whose
+// identifiers were all guarded and so link to no cluster. Repeating such a request then finds the contact it
+// created the first time instead of creating another one.
+func (s *service) guardedCluster(ctx context.Context, keys []domain.IdentifierKey) ([]*domain.Contact, error) {
+	contact, err := s.repo.GetContactWithIdentifiers(ctx, keys)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting contact with identifiers")
+	}
+	if contact == nil {
+		return nil, nil
+	}
+	return s.linkedCluster(ctx, contact.ContactID)
+}
+
+// flagIdentifiers stores the identifiers guardKeys found to be carried by too many contacts.
+func (s *service) flagIdentifiers(ctx context.Context, g *guarded) error {
+	if len(g.flag) == 0 {
+		return nil
+	}
+	if err := s.repo.CreateFlaggedIdentifiers(ctx, g.flag); err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while flagging identifiers")
+	}
+	return nil
+}
+
+// FlaggedIdentifiers returns up to limit flagged identifiers with an id greater than afterID, in id order. A limit
+// that is not positive returns the default page size; larger limits are capped.
+func (s *service) FlaggedIdentifiers(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.FlaggedIdentifier, error) {
+	flagged, err := s.repo.ListFlaggedIdentifiers(ctx, afterID, pageSize(limit))
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while listing flagged identifiers")
+	}
+	return flagged, nil
+}
+
+// pageSize returns the default page size for a limit that is not positive, and caps larger limits.
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	return min(limit, maxPageSize)
+}
+
+// unguardedKeys returns the keys that were followed.
+func unguardedKeys(keys []domain.IdentifierKey, g *guarded) []domain.IdentifierKey {
+	if len(g.keys) == 0 {
+		return keys
+	}
+	var unguarded []domain.IdentifierKey
+	for _, key := range keys {
+		if !g.keys[key] {
+			unguarded = append(unguarded, key)
+		}
+	}
+	return unguarded
+}
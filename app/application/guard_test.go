@@ -0,0 +1,173 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Identify_SharedIdentifierGuard ...
+func TestService_Identify_SharedIdentifierGuard(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	callCentre := phoneKey("+4930123456789")
+
+	tests := []struct {
+		Name            string
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary uint
+		ExpectedCreated bool
+	}{
+		{
+			Name: "Identifier carried by too many contacts is flagged and not followed",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				requested := keys(emailKey("george@hillvalley.edu"), callCentre)
+				repo.On("GetFlaggedIdentifiers", ctx, requested).Return([]*domain.FlaggedIdentifier{}, nil).Once()
+				repo.On("CountContactsByIdentifiers", ctx, requested).
+					Return(map[domain.IdentifierKey]int{callCentre: 3}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"))).
+					Return([]*domain.Contact{}, nil).Once()
+				repo.On("CreateFlaggedIdentifiers", ctx, []*domain.FlaggedIdentifier{
+					{Type: callCentre.Type, Value: callCentre.Value, ContactCount: 3},
+				}).Return(nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedPrecedence == "primary" && len(c.Identifiers) == 2
+				})).Return(newContact(4, "george@hillvalley.edu", callCentre.Value, 0, t0), nil).Once()
+			},
+			ExpectedPrimary: 4,
+			ExpectedCreated: true,
+		},
+		{
+			Name: "Flagged identifier is skipped without counting it again",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "george@hillvalley.edu", callCentre.Value, 0, t0)
+				repo.On("GetFlaggedIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"), callCentre)).
+					Return([]*domain.FlaggedIdentifier{
+						{FlaggedIdentifierID: 1, Type: callCentre.Type, Value: callCentre.Value, ContactCount: 3},
+					}, nil).Once()
+				repo.On("CountContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"))).
+					Return(map[domain.IdentifierKey]int{emailKey("george@hillvalley.edu"): 1}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"))).
+					Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{c1}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+			repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+			tt.Setup(ctx, repoMock)
+			repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+			repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+
+			service := application.NewService(repoMock, config.IdentityConfig{SharedIdentifierThreshold: 2})
+			result, err := service.Identify(ctx, identifyRequest("george@hillvalley.edu", callCentre.Value))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedPrimary, result.Contacts[0].ContactID)
+			assert.Equal(t, tt.ExpectedCreated, result.Created)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestService_Identify_OnlyGuardedIdentifiers ...
+func TestService_Identify_OnlyGuardedIdentifiers(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	callCentre := phoneKey("+4930123456789")
+	flagged := []*domain.FlaggedIdentifier{
+		{FlaggedIdentifierID: 1, Type: callCentre.Type, Value: callCentre.Value, ContactCount: 3},
+	}
+
+	tests := []struct {
+		Name            string
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary uint
+		ExpectedCreated bool
+	}{
+		{
+			Name: "First request creates a contact of its own",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactWithIdentifiers", ctx, keys(callCentre)).Return((*domain.Contact)(nil), nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedPrecedence == "primary" && len(c.Identifiers) == 1
+				})).Return(newContact(4, "", callCentre.Value, 0, t0), nil).Once()
+			},
+			ExpectedPrimary: 4,
+			ExpectedCreated: true,
+		},
+		{
+			Name: "Repeated request returns the contact carrying the same identifiers",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c4 := newContact(4, "", callCentre.Value, 0, t0)
+				repo.On("GetContactWithIdentifiers", ctx, keys(callCentre)).Return(c4, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{4}).Return([]*domain.Contact{c4}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(4)).Return([]*domain.Contact{c4}, nil).Once()
+			},
+			ExpectedPrimary: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+			repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+			repoMock.On("GetFlaggedIdentifiers", ctx, keys(callCentre)).Return(flagged, nil).Once()
+			tt.Setup(ctx, repoMock)
+			repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+			repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+
+			service := application.NewService(repoMock, config.IdentityConfig{SharedIdentifierThreshold: 2})
+			result, err := service.Identify(ctx, identifyRequest("", callCentre.Value))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedPrimary, result.Contacts[0].ContactID)
+			assert.Equal(t, tt.ExpectedCreated, result.Created)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestService_FlaggedIdentifiers ...
+func TestService_FlaggedIdentifiers(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Limit         int
+		ExpectedLimit int
+	}{
+		{Name: "Default page size", Limit: 0, ExpectedLimit: 100},
+		{Name: "Requested page size", Limit: 20, ExpectedLimit: 20},
+		{Name: "Capped page size", Limit: 5000, ExpectedLimit: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("ListFlaggedIdentifiers", ctx, uint(7), tt.ExpectedLimit).
+				Return([]*domain.FlaggedIdentifier{{FlaggedIdentifierID: 8}}, nil).Once()
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			flagged, err := service.FlaggedIdentifiers(ctx, 7, tt.Limit)
+
+			assert.NoError(t, err)
+			assert.Len(t, flagged, 1)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package application
+
+import (
+	"encoding/hex"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownIdentifierType is returned for identifiers whose type is not registered.
+	ErrUnknownIdentifierType = errors.New("[Service][LinkIdentity] unknown identifier type")
+	// ErrInvalidIdentifier is returned for identifiers whose value is rejected by their type.
+	ErrInvalidIdentifier = errors.New("[Service][LinkIdentity] invalid identifier")
+)
+
+// IdentifierValue is an identifier as it was received, before it is normalized.
+type IdentifierValue struct {
+	Type  string
+	Value string
+	// Verified reports that the customer proved to own the identifier, for instance with an OTP.
+	Verified bool
+}
+
+// IdentifierType describes how the values of one type of identifier are validated and normalized.
+type IdentifierType struct {
+	Name string
+	// Normalize validates the raw value and returns the normalized form it is stored and matched by.
+	Normalize func(raw string) (string, error)
+}
+
+// IdentifierRegistry holds the identifier types contacts can be linked by.
+type IdentifierRegistry struct {
+	types map[string]IdentifierType
+}
+
+// NewIdentifierRegistry ...
+func NewIdentifierRegistry(types ...IdentifierType) *IdentifierRegistry {
+	r := &IdentifierRegistry{types: make(map[string]IdentifierType, len(types))}
+	for _, t := range types {
+		r.Register(t)
+	}
+	return r
+}
+
+// NewDefaultIdentifierRegistry returns a registry of the built-in identifier types.
+func NewDefaultIdentifierRegistry(cfg config.IdentityConfig, emails EmailCanonicalizer) *IdentifierRegistry {
+	return NewIdentifierRegistry(
+		IdentifierType{
+			Name: domain.IdentifierTypeEmail,
+			Normalize: func(raw string) (string, error) {
+				address, err := mail.ParseAddress(raw)
+				if err != nil {
+					return "", err
+				}
+				return emails.Canonicalize(address.Address), nil
+			},
+		},
+		IdentifierType{
+			Name: domain.IdentifierTypePhone,
+			Normalize: func(raw string) (string, error) {
+				return NormalizePhone(raw, cfg.DefaultPhoneRegion)
+			},
+		},
+		IdentifierType{
+			Name: domain.IdentifierTypeDeviceID,
+			Normalize: func(raw string) (string, error) {
+				return strings.ToLower(raw), nil
+			},
+		},
+		IdentifierType{
+			Name: domain.IdentifierTypeLoyaltyID,
+			Normalize: func(raw string) (string, error) {
+				return strings.ToUpper(raw), nil
+			},
+		},
+		IdentifierType{
+			Name: domain.IdentifierTypeShippingAddressHash,
+			Normalize: func(raw string) (string, error) {
+				if b, err := hex.DecodeString(raw); err != nil || len(b) != 32 {
+					return "", errors.New("expected a hex encoded SHA-256 hash")
+				}
+				return strings.ToLower(raw), nil
+			},
+		},
+	)
+}
+
+// Register adds the identifier type, replacing any type registered under the same name.
+func (r *IdentifierRegistry) Register(t IdentifierType) {
+	r.types[t.Name] = t
+}
+
+// Has reports whether an identifier type is registered under the name.
+func (r *IdentifierRegistry) Has(typ string) bool {
+	_, ok := r.types[typ]
+	return ok
+}
+
+// Types returns the names of the registered types in alphabetical order.
+func (r *IdentifierRegistry) Types() []string {
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Normalize validates the raw value of an identifier of the given type and returns it as stored and matched.
+func (r *IdentifierRegistry) Normalize(typ, raw string) (*domain.Identifier, error) {
+	t, ok := r.types[typ]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownIdentifierType, "%q", typ)
+	}
+	raw = strings.TrimSpace(raw)
+	value, err := t.Normalize(raw)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidIdentifier, "%s %q: %s", typ, raw, err)
+	}
+	if value == "" {
+		return nil, errors.Wrapf(ErrInvalidIdentifier, "%s %q: empty value", typ, raw)
+	}
+	return &domain.Identifier{Type: typ, Value: value, RawValue: raw}, nil
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/pkg/errors"
+)
+
+// linkCause tells mergeCluster why it relinks contacts, so that it can record the change in the link events.
+type linkCause struct {
+	// action is recorded for every relinked contact. When it is empty, demoted primaries are recorded as
+	// LinkActionDemote and re-pointed secondaries as LinkActionLink.
+	action string
+	// keys are the identifiers that brought the clusters together. The event of a contact names the first of them
+	// carried by the cluster the contact belonged to.
+	keys []domain.IdentifierKey
+}
+
+// LinkEvents returns the link events of every contact currently in the cluster of the given contact, oldest first.
+func (s *service) LinkEvents(ctx context.Context, contactID uint) ([]*domain.LinkEvent, error) {
+	cluster, err := s.linkedCluster(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(cluster))
+	for _, c := range cluster {
+		ids = append(ids, c.ContactID)
+	}
+	events, err := s.repo.GetLinkEventsByContactIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting link events")
+	}
+	return events, nil
+}
+
+// newLinkEvent returns an event for the contact as it is before the change. The caller fills in the state after.
+func newLinkEvent(ctx context.Context, contact *domain.Contact, action string) *domain.LinkEvent {
+	return &domain.LinkEvent{
+		ContactID:        contact.ContactID,
+		Action:           action,
+		BeforeLinkedID:   contact.LinkedID,
+		BeforePrecedence: contact.LinkedPrecedence,
+		RequestID:        infrastructure.RequestID(ctx),
+	}
+}
+
+// createdEvent returns the event of a contact inserted because of the identifier. Its ContactID is set once the
+// contact has been inserted.
+func createdEvent(ctx context.Context, contact *domain.Contact, cause domain.IdentifierKey) *domain.LinkEvent {
+	event := newLinkEvent(ctx, &domain.Contact{ContactID: contact.ContactID}, domain.LinkActionCreate)
+	event.AfterLinkedID = contact.LinkedID
+	event.AfterPrecedence = contact.LinkedPrecedence
+	event.IdentifierType = cause.Type
+	event.IdentifierValue = cause.Value
+	return event
+}
+
+// recordLinkEvents appends the events to the link change log and the domain events they make up to the outbox,
+// in the transaction of the changes.
+func (s *service) recordLinkEvents(ctx context.Context, events ...*domain.LinkEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := s.repo.CreateLinkEvents(ctx, events); err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while recording link events")
+	}
+	outbox, err := outboxEvents(events)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateOutboxEvents(ctx, outbox); err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while writing outbox events")
+	}
+	return nil
+}
+
+// rootID returns the id of the primary the contact is linked to, or its own id if it is a primary.
+func rootID(c *domain.Contact) uint {
+	if c.LinkedID != 0 {
+		return c.LinkedID
+	}
+	return c.ContactID
+}
+
+// causeKey returns the first of the keys carried by a contact of the cluster linked to root.
+func causeKey(
+	cluster []*domain.Contact,
+	roots map[uint]uint,
+	root uint,
+	keys []domain.IdentifierKey,
+) (domain.IdentifierKey, bool) {
+	for _, key := range keys {
+		for _, c := range cluster {
+			if roots[c.ContactID] == root && c.HasIdentifier(key) {
+				return key, true
+			}
+		}
+	}
+	return domain.IdentifierKey{}, false
+}
@@ -2,8 +2,11 @@ package application
 
 import (
 	"context"
-	"database/sql"
+	"math/rand"
+	"strings"
+	"time"
 
+	"github.com/link-identity/app/config"
 	"github.com/link-identity/app/domain"
 	"github.com/link-identity/app/infrastructure/repository"
 
@@ -13,120 +16,721 @@ import (
 const (
 	primaryPrecedence   = "primary"
 	secondaryPrecedence = "secondary"
+
+	retryBackoff     = 20 * time.Millisecond
+	backfillPageSize = 500
 )
 
+// ErrMissingIdentifier is returned when a request carries no identifier.
+var ErrMissingIdentifier = errors.New("[Service][LinkIdentity] at least one identifier is required")
+
 // LinkIdentityService ...
 type LinkIdentityService interface {
-	Identify(ctx context.Context, email, phone string) ([]*domain.Contact, error)
+	Identify(ctx context.Context, req IdentifyRequest) (*IdentifyResult, error)
+	IdentifyBatch(ctx context.Context, reqs []IdentifyRequest) ([]BatchItemResult, error)
+	Backfill(ctx context.Context) (*BackfillReport, error)
+	Merge(ctx context.Context, req MergeRequest) (*MergeResult, error)
+	Split(ctx context.Context, req SplitRequest) (*SplitResult, error)
+	LinkEvents(ctx context.Context, contactID uint) ([]*domain.LinkEvent, error)
+	Observations(ctx context.Context, contactID uint) ([]*domain.Observation, error)
+	Timeline(ctx context.Context, contactID uint, offset, limit int) ([]*TimelineEntry, error)
+	GetCluster(ctx context.Context, contactID uint) ([]*domain.Contact, error)
+	FindCluster(ctx context.Context, value IdentifierValue) ([]*domain.Contact, error)
+	VerifyIdentifier(ctx context.Context, req VerifyRequest) error
+	FlaggedIdentifiers(ctx context.Context, afterID uint, limit int) ([]*domain.FlaggedIdentifier, error)
+	DenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error)
+	CreateDenylistEntry(ctx context.Context, req DenylistRequest) (*domain.DenylistEntry, error)
+	UpdateDenylistEntry(ctx context.Context, id uint, req DenylistRequest) (*domain.DenylistEntry, error)
+	DeleteDenylistEntry(ctx context.Context, id uint) error
+	CreateWebhookSubscription(ctx context.Context, req WebhookSubscriptionRequest) (*domain.WebhookSubscription, error)
+	WebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id uint) error
+	WebhookDeadLetters(ctx context.Context, afterID uint, limit int) ([]*domain.WebhookDeadLetter, error)
+	ReplayWebhookDeadLetter(ctx context.Context, id uint) (*domain.WebhookDelivery, error)
+	ExportClusters(ctx context.Context, fn func(record *ClusterRecord) error) error
+	Erase(ctx context.Context, req ErasureRequest) (*domain.ErasureReceipt, error)
+}
+
+// IdentifyRequest ...
+type IdentifyRequest struct {
+	// Identifiers must hold at least one identifier of a registered type. Blank values count as not set. Every
+	// identifier is normalized by its type before it is stored or matched.
+	Identifiers []IdentifierValue
+	// CreatedAt dates the contact the request creates, for instance with the time of an imported historical
+	// order. The contact then takes part in the election of the primary with that date. It defaults to now.
+	CreatedAt *time.Time
+	// Observation, if set, is recorded for the contact the request creates or, when it creates none, for the
+	// primary of the cluster it matches. The link events of the request refer to it.
+	Observation *Observation
+	// DryRun plans the operations without writing anything.
+	DryRun bool
+}
+
+// IdentifyResult ...
+type IdentifyResult struct {
+	// Contacts is the consolidated cluster the identifiers belong to.
+	Contacts []*domain.Contact
+	// Created reports whether a new contact was inserted. It is false when the cluster already held every
+	// identifier of the request.
+	Created bool
+	// Operations lists the writes made, or planned in a dry run: the contact created, if any, and every contact
+	// demoted or re-pointed to another primary. Contacts still to be created have no ContactID.
+	Operations []*domain.LinkEvent
+	// DryRun reports that the operations were planned only. Contacts then holds the cluster as it would be.
+	DryRun bool
+	// NewPrimary reports, in a dry run, that the contact to be created would become the primary of the cluster. The
+	// operations re-pointing contacts to it then have an AfterLinkedID of 0, the placeholder for that contact.
+	NewPrimary bool
+}
+
+// BackfillReport ...
+type BackfillReport struct {
+	// Scanned is the number of contacts read.
+	Scanned int
+	// Normalized is the number of contacts with at least one identifier rewritten.
+	Normalized int
+	// Invalid is the number of identifiers that cannot be normalized any more. They are left untouched.
+	Invalid int
+	// MergedClusters is the number of clusters merged into another one because they now share an identifier.
+	MergedClusters int
 }
 
 type service struct {
-	repo repository.ContactRepository
+	repo        repository.ContactRepository
+	cfg         config.IdentityConfig
+	emails      EmailCanonicalizer
+	identifiers *IdentifierRegistry
+	election    ElectionPolicy
+	denylists   *denylistCache
+	// webhookHosts are the hosts webhooks may target over http and at internal addresses.
+	webhookHosts []string
+}
+
+// ServiceOption customizes the service returned by NewService.
+type ServiceOption func(s *service)
+
+// WithEmailCanonicalizer replaces the EmailCanonicalizer built from the configuration.
+func WithEmailCanonicalizer(c EmailCanonicalizer) ServiceOption {
+	return func(s *service) {
+		s.emails = c
+	}
+}
+
+// WithIdentifierRegistry replaces the registry of built-in identifier types.
+func WithIdentifierRegistry(r *IdentifierRegistry) ServiceOption {
+	return func(s *service) {
+		s.identifiers = r
+	}
+}
+
+// WithElectionPolicy replaces the default OldestFirst election of the primary of a cluster.
+func WithElectionPolicy(p ElectionPolicy) ServiceOption {
+	return func(s *service) {
+		s.election = p
+	}
+}
+
+// WithWebhookAllowedHosts lets webhook subscriptions target the hosts over http and at loopback, private or
+// link-local addresses, for instance a receiver running next to the service.
+func WithWebhookAllowedHosts(hosts []string) ServiceOption {
+	return func(s *service) {
+		s.webhookHosts = hosts
+	}
 }
 
 // NewService ...
-func NewService(contactRepo repository.ContactRepository) LinkIdentityService {
-	return &service{
+func NewService(
+	contactRepo repository.ContactRepository,
+	cfg config.IdentityConfig,
+	opts ...ServiceOption,
+) LinkIdentityService {
+	s := &service{
 		repo: contactRepo,
+		cfg:  cfg,
+		emails: NewEmailCanonicalizer(
+			newEmailDomainRules(cfg.EmailPlusTagDomains, cfg.EmailDotInsensitiveDomains),
+		),
+		denylists: newDenylistCache(time.Duration(cfg.DenylistCacheTTLMillis) * time.Millisecond),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.identifiers == nil {
+		s.identifiers = NewDefaultIdentifierRegistry(cfg, s.emails)
 	}
+	if s.election == nil {
+		s.election = OldestFirst()
+	}
+	return s
 }
 
-func (s *service) Identify(ctx context.Context, email, phone string) ([]*domain.Contact, error) {
-	existingContactByEmail, err := s.repo.GetContactByEmail(ctx, email)
+func (s *service) Identify(ctx context.Context, req IdentifyRequest) (*IdentifyResult, error) {
+	identifiers, err := s.normalizeIdentifiers(req.Identifiers)
+	if err != nil {
+		return nil, err
+	}
+	observation, err := normalizeObservation(req.Observation)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *IdentifyResult
+	err = s.inLockedTransaction(ctx, identifierKeys(identifiers), func(ctx context.Context) error {
+		var err error
+		result, err = s.identify(ctx, identifiers, observation, req.CreatedAt, req.DryRun)
+		return err
+	})
 	if err != nil {
-		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by email")
+		return nil, err
 	}
+	return result, nil
+}
 
-	existingContactByPhone, err := s.repo.GetContactByPhone(ctx, phone)
+// identify links the identifiers into the identity graph. It must run in a transaction holding the locks of the
+// identifiers, see inLockedTransaction, so concurrent calls sharing an identifier are linked one after the other.
+// Calls that only meet further along the graph are kept apart by the serializable isolation of the transaction.
+//
+// A new contact is only inserted when the request carries an identifier the cluster does not hold yet, so
+// repeating a request never adds rows. In a dry run, the operations are planned the same way but not applied.
+func (s *service) identify(
+	ctx context.Context,
+	identifiers []*domain.Identifier,
+	observation *Observation,
+	createdAt *time.Time,
+	dryRun bool,
+) (*IdentifyResult, error) {
+	keys := identifierKeys(identifiers)
+	cluster, guarded, err := s.resolve(ctx, identifiers)
 	if err != nil {
-		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by phone")
+		return nil, err
+	}
+	if len(cluster) == 0 && len(unguardedKeys(keys, guarded)) == 0 {
+		if cluster, err = s.guardedCluster(ctx, keys); err != nil {
+			return nil, err
+		}
 	}
 
 	contact := &domain.Contact{
-		Email:            sql.NullString{String: email, Valid: true},
-		Phone:            sql.NullString{String: phone, Valid: true},
+		Model:            domain.Model{CreatedAt: createdAt},
+		Identifiers:      newIdentifiers(identifiers),
 		LinkedPrecedence: primaryPrecedence,
 	}
 
-	switch {
-	case existingContactByEmail != nil && existingContactByPhone != nil:
-		{
+	create := len(cluster) == 0 || !containsAll(cluster, keys)
+	// without a new contact to carry them, the verifications of the request are stored on the identifiers it matched.
+	var verifications []*domain.Identifier
+	if !create {
+		verifications = verifyMatched(cluster, identifiers)
+	}
+	// a contact that wins the election of its cluster, for instance one dated before the primary as imported
+	// history can be, is inserted as a primary first and then takes part in the election like any other primary.
+	precedes := false
+	if create && len(cluster) > 0 {
+		candidates := append(append([]*domain.Contact{}, cluster...), contact)
+		precedes = s.election.Elect(candidates) == contact
+	}
+
+	var operations []*domain.LinkEvent
+	var created *domain.LinkEvent
+	if precedes {
+		created = createdEvent(ctx, contact, missingKey(cluster, keys))
+		operations = append(operations, created)
+		if !dryRun {
+			contact, err = s.repo.CreateContact(ctx, contact)
+			if err != nil {
+				return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while creating contact")
+			}
+			created.ContactID = contact.ContactID
+		}
+		cluster = append(cluster, contact)
+	}
+
+	var primary *domain.Contact
+	var relinks []relink
+	if len(cluster) > 0 {
+		primary, relinks = s.planMerge(ctx, cluster, linkCause{keys: unguardedKeys(keys, guarded)})
+		operations = append(operations, relinkEvents(relinks)...)
+	}
+
+	if create && !precedes {
+		if primary != nil {
 			contact.LinkedPrecedence = secondaryPrecedence
-			if existingContactByPhone.ContactID == existingContactByEmail.ContactID {
-				contact.LinkedID = existingContactByEmail.ContactID
-				if existingContactByEmail.LinkedPrecedence == secondaryPrecedence {
-					contact.LinkedID = existingContactByEmail.LinkedID
+			contact.LinkedID = primary.ContactID
+		}
+		created = createdEvent(ctx, contact, missingKey(cluster, keys))
+		operations = append(operations, created)
+	}
+
+	if dryRun {
+		for _, r := range relinks {
+			r.contact.LinkedID = r.event.AfterLinkedID
+			r.contact.LinkedPrecedence = r.event.AfterPrecedence
+		}
+		if create && !precedes {
+			cluster = append(cluster, contact)
+		}
+		return &IdentifyResult{
+			Contacts: cluster, Created: create, Operations: operations, DryRun: true, NewPrimary: precedes,
+		}, nil
+	}
+
+	if err := s.flagIdentifiers(ctx, guarded); err != nil {
+		return nil, err
+	}
+	if err := s.applyRelinks(ctx, relinks); err != nil {
+		return nil, err
+	}
+	if err := s.storeVerifications(ctx, verifications); err != nil {
+		return nil, err
+	}
+	if create && !precedes {
+		contact, err = s.repo.CreateContact(ctx, contact)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while creating contact")
+		}
+		created.ContactID = contact.ContactID
+	}
+
+	observedID := contact.ContactID
+	if !create {
+		observedID = primary.ContactID
+	}
+	observationID, err := s.recordObservation(ctx, observation, observedID)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range operations {
+		op.ObservationID = observationID
+	}
+	if err := s.recordLinkEvents(ctx, operations...); err != nil {
+		return nil, err
+	}
+
+	if primary == nil {
+		return &IdentifyResult{Contacts: []*domain.Contact{contact}, Created: true, Operations: operations}, nil
+	}
+
+	secondaryContacts, err := s.repo.GetAllSecondaryContacts(ctx, primary.ContactID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting secondary contacts")
+	}
+
+	return &IdentifyResult{Contacts: secondaryContacts, Created: create, Operations: operations}, nil
+}
+
+// Backfill normalizes the raw value of every stored identifier again, for instance after the phone region or the
+// email rules changed. Every contact with a rewritten identifier is relinked, which merges the clusters that now
+// share an identifier. Each contact is handled in its own transaction, so an interrupted backfill can simply be
+// run again.
+func (s *service) Backfill(ctx context.Context) (*BackfillReport, error) {
+	report := &BackfillReport{}
+	var afterID uint
+	for {
+		contacts, err := s.repo.ListContacts(ctx, afterID, backfillPageSize)
+		if err != nil {
+			return report, errors.Wrapf(err, "[Service][LinkIdentity] error while listing contacts")
+		}
+		if len(contacts) == 0 {
+			return report, nil
+		}
+
+		for _, c := range contacts {
+			afterID = c.ContactID
+			report.Scanned++
+
+			var changed []*domain.Identifier
+			for _, i := range c.Identifiers {
+				normalized, err := s.identifiers.Normalize(i.Type, i.RawValue)
+				if err != nil {
+					report.Invalid++
+					continue
 				}
-			} else {
-				if existingContactByEmail.CreatedAt.After(*existingContactByPhone.CreatedAt) {
-					contact.LinkedID = existingContactByPhone.ContactID
-					if existingContactByPhone.LinkedPrecedence == secondaryPrecedence {
-						contact.LinkedID = existingContactByPhone.LinkedID
-					}
-					if existingContactByEmail.LinkedPrecedence == primaryPrecedence ||
-						existingContactByEmail.LinkedID != contact.LinkedID {
-
-						existingContactByEmail.LinkedPrecedence = secondaryPrecedence
-						existingContactByEmail.LinkedID = contact.LinkedID
-						_, err = s.repo.UpdateContact(ctx, existingContactByEmail)
-						if err != nil {
-							return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while updating contact")
-						}
-					}
-				} else {
-					contact.LinkedID = existingContactByEmail.ContactID
-					if existingContactByEmail.LinkedPrecedence == secondaryPrecedence {
-						contact.LinkedID = existingContactByEmail.LinkedID
-					}
-					if existingContactByPhone.LinkedPrecedence == primaryPrecedence ||
-						existingContactByPhone.LinkedID != contact.LinkedID {
-
-						existingContactByPhone.LinkedPrecedence = secondaryPrecedence
-						existingContactByPhone.LinkedID = contact.LinkedID
-						_, err = s.repo.UpdateContact(ctx, existingContactByPhone)
-						if err != nil {
-							return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while updating contact")
-						}
-					}
+				if normalized.Value != i.Value {
+					changed = append(changed, &domain.Identifier{
+						IdentifierID: i.IdentifierID,
+						Type:         i.Type,
+						Value:        normalized.Value,
+						VerifiedAt:   i.VerifiedAt,
+					})
 				}
 			}
-			secondaryContacts, err := s.repo.GetAllSecondaryContacts(ctx, contact.LinkedID)
+			if len(changed) == 0 {
+				continue
+			}
+
+			var merged int
+			err = s.inLockedTransaction(ctx, identifierKeys(changed), func(ctx context.Context) error {
+				var err error
+				merged, err = s.renormalize(ctx, changed)
+				return err
+			})
 			if err != nil {
-				return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting secondary contacts")
+				return report, err
 			}
-			return secondaryContacts, nil
+			report.Normalized++
+			report.MergedClusters += merged
 		}
-	case existingContactByEmail != nil:
-		{
-			contact.LinkedPrecedence = secondaryPrecedence
-			contact.LinkedID = existingContactByEmail.ContactID
-			if existingContactByEmail.LinkedPrecedence == secondaryPrecedence {
-				contact.LinkedID = existingContactByEmail.LinkedID
+	}
+}
+
+// renormalize stores the new values of the identifiers and merges every cluster carrying one of them. It returns
+// the number of clusters merged into another one.
+func (s *service) renormalize(ctx context.Context, identifiers []*domain.Identifier) (int, error) {
+	keys := identifierKeys(identifiers)
+	for _, i := range identifiers {
+		if err := s.repo.UpdateIdentifierValue(ctx, i.IdentifierID, i.Value); err != nil {
+			return 0, errors.Wrapf(err, "[Service][LinkIdentity] error while updating identifier")
+		}
+	}
+
+	cluster, guarded, err := s.resolve(ctx, identifiers)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.flagIdentifiers(ctx, guarded); err != nil {
+		return 0, err
+	}
+	primaries := 0
+	for _, c := range cluster {
+		if c.LinkedPrecedence == primaryPrecedence {
+			primaries++
+		}
+	}
+	if _, err := s.mergeCluster(ctx, cluster, linkCause{keys: unguardedKeys(keys, guarded)}); err != nil {
+		return 0, err
+	}
+	if primaries == 0 {
+		return 0, nil
+	}
+	return primaries - 1, nil
+}
+
+// inTransaction runs fn in a single database transaction. When the database aborts it because of a conflicting
+// concurrent transaction, fn is run again from scratch after a short randomised backoff.
+func (s *service) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.retryConflicts(ctx, func() error {
+		return s.repo.WithTransaction(ctx, fn)
+	})
+}
+
+// inLockedTransaction is inTransaction holding the locks of the identifiers, so that calls sharing one of them run
+// one after the other instead of conflicting. Only calls meeting through other identifiers are retried. Without
+// identifiers it is inTransaction.
+func (s *service) inLockedTransaction(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+	fn func(ctx context.Context) error,
+) error {
+	if len(keys) == 0 {
+		return s.inTransaction(ctx, fn)
+	}
+	return s.retryConflicts(ctx, func() error {
+		return s.repo.WithLockedTransaction(ctx, lockKeys(keys), fn)
+	})
+}
+
+// retryConflicts runs the transaction up to MaxTransactionAttempts times while it fails with a serialization
+// failure.
+func (s *service) retryConflicts(ctx context.Context, transaction func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := transaction()
+		if err == nil || !errors.Is(err, repository.ErrSerializationFailure) || attempt >= s.cfg.MaxTransactionAttempts {
+			return err
+		}
+
+		backoff := time.Duration(attempt)*retryBackoff + time.Duration(rand.Int63n(int64(retryBackoff)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// normalizeIdentifiers normalizes the identifiers of a request and drops blank and duplicate ones. Verified
+// identifiers are dated now; a duplicate counts as verified if any of its copies is.
+func (s *service) normalizeIdentifiers(values []IdentifierValue) ([]*domain.Identifier, error) {
+	var identifiers []*domain.Identifier
+	seen := make(map[domain.IdentifierKey]*domain.Identifier)
+	now := time.Now()
+	for _, v := range values {
+		if strings.TrimSpace(v.Value) == "" {
+			continue
+		}
+		identifier, err := s.identifiers.Normalize(v.Type, v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if first, ok := seen[identifier.Key()]; ok {
+			identifier = first
+		} else {
+			seen[identifier.Key()] = identifier
+			identifiers = append(identifiers, identifier)
+		}
+		if v.Verified {
+			identifier.VerifiedAt = &now
+		}
+	}
+	if len(identifiers) == 0 {
+		return nil, ErrMissingIdentifier
+	}
+	return identifiers, nil
+}
+
+// resolveCluster walks the identity graph starting from the given identifiers. It returns every contact reachable
+// through a shared identifier or a link between two contacts, across as many clusters as the walk touches.
+// Identifiers dropped by guardKeys are not followed; they are returned as well, so that the caller can flag them.
+func (s *service) resolveCluster(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) ([]*domain.Contact, *guarded, error) {
+	g, err := s.newGuarded(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[domain.IdentifierKey]bool)
+	queriedIDs := make(map[uint]bool)
+	visited := make(map[uint]bool)
+	var cluster []*domain.Contact
+
+	keys = unseenKeys(keys, seen)
+	for len(keys) > 0 {
+		keys, err = s.guardKeys(ctx, keys, g)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		matches, err := s.repo.GetContactsByIdentifiers(ctx, keys)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by identifiers")
+		}
+		keys = nil
+
+		ids := unqueriedIDs(matches, queriedIDs)
+		for len(ids) > 0 {
+			linked, err := s.repo.GetContactsByLinkedIDs(ctx, ids)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting linked contacts")
 			}
+
+			var next []*domain.Contact
+			for _, c := range linked {
+				if visited[c.ContactID] {
+					continue
+				}
+				visited[c.ContactID] = true
+				cluster = append(cluster, c)
+				next = append(next, c)
+				keys = append(keys, unseenKeys(identifierKeys(c.Identifiers), seen)...)
+			}
+			ids = unqueriedIDs(next, queriedIDs)
 		}
-	case existingContactByPhone != nil:
-		{
-			contact.LinkedPrecedence = secondaryPrecedence
-			contact.LinkedID = existingContactByPhone.ContactID
-			if existingContactByPhone.LinkedPrecedence == secondaryPrecedence {
-				contact.LinkedID = existingContactByPhone.LinkedID
+	}
+
+	return cluster, g, nil
+}
+
+// relink is a planned change to the link of a contact. The event holds the link before and after the change.
+type relink struct {
+	contact *domain.Contact
+	event   *domain.LinkEvent
+}
+
+// mergeCluster elects the primary of the cluster and re-points every other contact to it, demoting the other
+// primaries of the clusters being merged. Every contact it relinks is recorded in the link events.
+func (s *service) mergeCluster(
+	ctx context.Context,
+	cluster []*domain.Contact,
+	cause linkCause,
+) (*domain.Contact, error) {
+	primary, relinks := s.planMerge(ctx, cluster, cause)
+	if err := s.applyRelinks(ctx, relinks); err != nil {
+		return nil, err
+	}
+	if err := s.recordLinkEvents(ctx, relinkEvents(relinks)...); err != nil {
+		return nil, err
+	}
+	return primary, nil
+}
+
+// planMerge returns the primary mergeCluster elects and the changes it makes, without changing anything.
+func (s *service) planMerge(
+	ctx context.Context,
+	cluster []*domain.Contact,
+	cause linkCause,
+) (*domain.Contact, []relink) {
+	primary := s.election.Elect(cluster)
+	roots := make(map[uint]uint, len(cluster))
+	for _, c := range cluster {
+		roots[c.ContactID] = rootID(c)
+	}
+
+	var relinks []relink
+	for _, c := range cluster {
+		linkedID, precedence := primary.ContactID, secondaryPrecedence
+		if c.ContactID == primary.ContactID {
+			linkedID, precedence = 0, primaryPrecedence
+		}
+		if c.LinkedID == linkedID && c.LinkedPrecedence == precedence {
+			continue
+		}
+
+		event := newLinkEvent(ctx, c, cause.action)
+		if event.Action == "" {
+			event.Action = domain.LinkActionLink
+			if c.LinkedPrecedence == primaryPrecedence && precedence == secondaryPrecedence {
+				event.Action = domain.LinkActionDemote
 			}
 		}
+		if key, ok := causeKey(cluster, roots, roots[c.ContactID], cause.keys); ok {
+			event.IdentifierType, event.IdentifierValue = key.Type, key.Value
+		}
+		event.AfterLinkedID, event.AfterPrecedence = linkedID, precedence
+		relinks = append(relinks, relink{contact: c, event: event})
 	}
+	return primary, relinks
+}
 
-	contact, err = s.repo.CreateContact(ctx, contact)
-	if err != nil {
-		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while creating contact")
+// applyRelinks stores the planned links of the contacts.
+func (s *service) applyRelinks(ctx context.Context, relinks []relink) error {
+	for _, r := range relinks {
+		r.contact.LinkedID = r.event.AfterLinkedID
+		r.contact.LinkedPrecedence = r.event.AfterPrecedence
+		if _, err := s.repo.UpdateContact(ctx, r.contact); err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while updating contact")
+		}
 	}
+	return nil
+}
 
-	if contact.LinkedPrecedence == primaryPrecedence {
-		return []*domain.Contact{contact}, nil
+func relinkEvents(relinks []relink) []*domain.LinkEvent {
+	events := make([]*domain.LinkEvent, 0, len(relinks))
+	for _, r := range relinks {
+		events = append(events, r.event)
 	}
+	return events
+}
 
-	secondaryContacts, err := s.repo.GetAllSecondaryContacts(ctx, contact.LinkedID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting secondary contacts")
+// electPrimary returns the oldest primary of the cluster, by isOlder. A cluster without any primary falls back to
+// its oldest contact.
+func electPrimary(cluster []*domain.Contact) *domain.Contact {
+	var primary, oldest *domain.Contact
+	for _, c := range cluster {
+		if oldest == nil || isOlder(c, oldest) {
+			oldest = c
+		}
+		if c.LinkedPrecedence == primaryPrecedence && (primary == nil || isOlder(c, primary)) {
+			primary = c
+		}
 	}
+	if primary == nil {
+		return oldest
+	}
+	return primary
+}
 
-	return secondaryContacts, nil
+// isOlder reports whether a ranks before b by age: the earlier CreatedAt first, then the lower ContactID. A
+// contact not stored yet, without a CreatedAt or a ContactID, ranks after the stored ones.
+func isOlder(a, b *domain.Contact) bool {
+	if a.CreatedAt != nil && b.CreatedAt != nil && !a.CreatedAt.Equal(*b.CreatedAt) {
+		return a.CreatedAt.Before(*b.CreatedAt)
+	}
+	if (a.CreatedAt == nil) != (b.CreatedAt == nil) {
+		return b.CreatedAt == nil
+	}
+	if (a.ContactID == 0) != (b.ContactID == 0) {
+		return b.ContactID == 0
+	}
+	return a.ContactID < b.ContactID
+}
+
+// containsAll reports whether the contacts of the cluster carry every one of the identifiers.
+func containsAll(cluster []*domain.Contact, keys []domain.IdentifierKey) bool {
+	for _, key := range keys {
+		found := false
+		for _, c := range cluster {
+			if c.HasIdentifier(key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// missingKey returns the first of the identifiers no contact of the cluster carries.
+func missingKey(cluster []*domain.Contact, keys []domain.IdentifierKey) domain.IdentifierKey {
+	for _, key := range keys {
+		if !containsAll(cluster, []domain.IdentifierKey{key}) {
+			return key
+		}
+	}
+	return domain.IdentifierKey{}
+}
+
+// newIdentifiers returns copies of the identifiers that are not stored yet, so that an attempt that was rolled
+// back leaves no ids behind for the next one.
+func newIdentifiers(identifiers []*domain.Identifier) []*domain.Identifier {
+	copies := make([]*domain.Identifier, 0, len(identifiers))
+	for _, i := range identifiers {
+		copies = append(copies, &domain.Identifier{
+			Type:       i.Type,
+			Value:      i.Value,
+			RawValue:   i.RawValue,
+			VerifiedAt: i.VerifiedAt,
+		})
+	}
+	return copies
+}
+
+func identifierKeys(identifiers []*domain.Identifier) []domain.IdentifierKey {
+	keys := make([]domain.IdentifierKey, 0, len(identifiers))
+	for _, i := range identifiers {
+		keys = append(keys, i.Key())
+	}
+	return keys
+}
+
+// lockKeys returns the advisory lock keys of the given identifiers.
+func lockKeys(keys []domain.IdentifierKey) []string {
+	locks := make([]string, 0, len(keys))
+	for _, key := range keys {
+		locks = append(locks, key.String())
+	}
+	return locks
+}
+
+// unseenKeys returns the keys with a value that are not in seen yet and marks them as seen.
+func unseenKeys(keys []domain.IdentifierKey, seen map[domain.IdentifierKey]bool) []domain.IdentifierKey {
+	var unseen []domain.IdentifierKey
+	for _, key := range keys {
+		if key.Value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unseen = append(unseen, key)
+	}
+	return unseen
+}
+
+// unqueriedIDs returns the ids of the contacts, and of the contacts they are linked to, that have not been
+// queried yet and marks them as queried.
+func unqueriedIDs(contacts []*domain.Contact, queried map[uint]bool) []uint {
+	var ids []uint
+	for _, c := range contacts {
+		for _, id := range []uint{c.ContactID, c.LinkedID} {
+			if id == 0 || queried[id] {
+				continue
+			}
+			queried[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
@@ -0,0 +1,448 @@
+package application_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure/repository"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newContact(id uint, email, phone string, linkedID uint, createdAt time.Time) *domain.Contact {
+	precedence := "primary"
+	if linkedID != 0 {
+		precedence = "secondary"
+	}
+	contact := &domain.Contact{
+		Model:            domain.Model{CreatedAt: &createdAt},
+		ContactID:        id,
+		LinkedID:         linkedID,
+		LinkedPrecedence: precedence,
+	}
+	if email != "" {
+		contact.Identifiers = append(contact.Identifiers, &domain.Identifier{
+			ContactID: id, Type: domain.IdentifierTypeEmail, Value: strings.ToLower(email), RawValue: email,
+		})
+	}
+	if phone != "" {
+		contact.Identifiers = append(contact.Identifiers, &domain.Identifier{
+			ContactID: id, Type: domain.IdentifierTypePhone, Value: phone, RawValue: phone,
+		})
+	}
+	return contact
+}
+
+func identifyRequest(email, phone string) application.IdentifyRequest {
+	return application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+		{Type: domain.IdentifierTypeEmail, Value: email},
+		{Type: domain.IdentifierTypePhone, Value: phone},
+	}}
+}
+
+func emailKey(email string) domain.IdentifierKey {
+	return domain.IdentifierKey{Type: domain.IdentifierTypeEmail, Value: email}
+}
+
+func phoneKey(phone string) domain.IdentifierKey {
+	return domain.IdentifierKey{Type: domain.IdentifierTypePhone, Value: phone}
+}
+
+func keys(keys ...domain.IdentifierKey) []domain.IdentifierKey {
+	return keys
+}
+
+// TestService_Identify ...
+func TestService_Identify(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name            string
+		Request         application.IdentifyRequest
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary uint
+		ExpectedCreated bool
+	}{
+		{
+			Name:    "New customer creates a primary contact",
+			Request: identifyRequest("doc@hillvalley.edu", "+4917611111111"),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("doc@hillvalley.edu"), phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedPrecedence == "primary" && c.LinkedID == 0 && len(c.Identifiers) == 2
+				})).Return(newContact(1, "doc@hillvalley.edu", "+4917611111111", 0, t0), nil).Once()
+			},
+			ExpectedPrimary: 1,
+			ExpectedCreated: true,
+		},
+		{
+			Name:    "New phone for a known email creates a secondary of the primary",
+			Request: identifyRequest("mcfly@hillvalley.edu", "+4917622222222"),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "lorraine@hillvalley.edu", "+4917611111111", 0, t0)
+				secondary := newContact(2, "mcfly@hillvalley.edu", "+4917611111111", 1, t0.Add(time.Hour))
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("mcfly@hillvalley.edu"), phoneKey("+4917622222222"))).
+					Return([]*domain.Contact{secondary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 1}).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("lorraine@hillvalley.edu"), phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedPrecedence == "secondary" && c.LinkedID == 1
+				})).Return(newContact(3, "mcfly@hillvalley.edu", "+4917622222222", 1, t0.Add(2*time.Hour)), nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+			ExpectedCreated: true,
+		},
+		{
+			Name:    "Repeating a known email and phone returns the cluster without creating a contact",
+			Request: identifyRequest("mcfly@hillvalley.edu", "+4917611111111"),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "lorraine@hillvalley.edu", "+4917611111111", 0, t0)
+				secondary := newContact(2, "mcfly@hillvalley.edu", "+4917611111111", 1, t0.Add(time.Hour))
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("mcfly@hillvalley.edu"), phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1, 2}).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("lorraine@hillvalley.edu"))).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+		{
+			Name:    "Email is matched by its canonical form",
+			Request: identifyRequest("E.Brown+FluxKart@Gmail.com", ""),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "ebrown@gmail.com", "+4917611111111", 0, t0)
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("ebrown@gmail.com"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{primary}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+		{
+			Name:    "Phone is matched and stored in E.164",
+			Request: identifyRequest("", "+49 (176) 2222 2222"),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917622222222"))).
+					Return([]*domain.Contact{}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return len(c.Identifiers) == 1 && c.Identifiers[0].Value == "+4917622222222" &&
+						c.Identifiers[0].RawValue == "+49 (176) 2222 2222"
+				})).Return(newContact(2, "", "+4917622222222", 0, t0), nil).Once()
+			},
+			ExpectedPrimary: 2,
+			ExpectedCreated: true,
+		},
+		{
+			Name: "Identifiers of any registered type link contacts",
+			Request: application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+				{Type: domain.IdentifierTypeDeviceID, Value: "6F9619FF-8B86-D011-B42D-00C04FC964FF"},
+			}},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				device := domain.IdentifierKey{Type: domain.IdentifierTypeDeviceID, Value: "6f9619ff-8b86-d011-b42d-00c04fc964ff"}
+				primary := newContact(1, "doc@hillvalley.edu", "", 0, t0)
+				primary.Identifiers = append(primary.Identifiers, &domain.Identifier{
+					ContactID: 1, Type: device.Type, Value: device.Value, RawValue: device.Value,
+				})
+				repo.On("GetContactsByIdentifiers", ctx, keys(device)).Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("doc@hillvalley.edu"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{primary}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+		{
+			Name: "Historical contact older than the primary becomes the primary",
+			Request: func() application.IdentifyRequest {
+				req := identifyRequest("lorraine@hillvalley.edu", "+4917611111111")
+				createdAt := t0.Add(-24 * time.Hour)
+				req.CreatedAt = &createdAt
+				return req
+			}(),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "", "+4917611111111", 0, t0)
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("lorraine@hillvalley.edu"), phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedPrecedence == "primary" && c.CreatedAt.Equal(t0.Add(-24*time.Hour))
+				})).Return(newContact(2, "lorraine@hillvalley.edu", "+4917611111111", 0, t0.Add(-24*time.Hour)), nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 1 && c.LinkedID == 2 && c.LinkedPrecedence == "secondary"
+				})).Return(&domain.Contact{}, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(2)).
+					Return([]*domain.Contact{newContact(2, "lorraine@hillvalley.edu", "+4917611111111", 0, t0), primary}, nil).Once()
+			},
+			ExpectedPrimary: 2,
+			ExpectedCreated: true,
+		},
+		{
+			Name:    "Identifiers from two clusters merge every contact under the oldest primary",
+			Request: identifyRequest("biff@hillvalley.edu", "+4917644444444"),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0)
+				c2 := newContact(2, "biff@hillvalley.edu", "+4917622222222", 1, t0.Add(time.Hour))
+				c3 := newContact(3, "marty@hillvalley.edu", "+4917633333333", 0, t0.Add(2*time.Hour))
+				c4 := newContact(4, "emmett@hillvalley.edu", "+4917644444444", 3, t0.Add(3*time.Hour))
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("biff@hillvalley.edu"), phoneKey("+4917644444444"))).
+					Return([]*domain.Contact{c2, c4}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 1, 4, 3}).
+					Return([]*domain.Contact{c1, c2, c3, c4}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(
+					emailKey("george@hillvalley.edu"), phoneKey("+4917611111111"), phoneKey("+4917622222222"),
+					emailKey("marty@hillvalley.edu"), phoneKey("+4917633333333"), emailKey("emmett@hillvalley.edu"),
+				)).Return([]*domain.Contact{c1, c2, c3, c4}, nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return (c.ContactID == 3 || c.ContactID == 4) && c.LinkedID == 1 && c.LinkedPrecedence == "secondary"
+				})).Return(&domain.Contact{}, nil).Twice()
+				repo.On("CreateLinkEvents", ctx, []*domain.LinkEvent{
+					{
+						ContactID: 3, Action: domain.LinkActionDemote,
+						BeforeLinkedID: 0, BeforePrecedence: "primary", AfterLinkedID: 1, AfterPrecedence: "secondary",
+						IdentifierType: domain.IdentifierTypePhone, IdentifierValue: "+4917644444444",
+					},
+					{
+						ContactID: 4, Action: domain.LinkActionLink,
+						BeforeLinkedID: 3, BeforePrecedence: "secondary", AfterLinkedID: 1, AfterPrecedence: "secondary",
+						IdentifierType: domain.IdentifierTypePhone, IdentifierValue: "+4917644444444",
+					},
+				}).Return(nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{c1, c2, c3, c4}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+			repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+			tt.Setup(ctx, repoMock)
+			repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+			repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+
+			service := application.NewService(repoMock, config.IdentityConfig{
+				EmailPlusTagDomains:        []string{"gmail.com"},
+				EmailDotInsensitiveDomains: []string{"gmail.com"},
+			})
+			result, err := service.Identify(ctx, tt.Request)
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, result.Contacts)
+			assert.Equal(t, tt.ExpectedPrimary, result.Contacts[0].ContactID)
+			assert.Equal(t, tt.ExpectedCreated, result.Created)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestService_Identify_DryRun ...
+func TestService_Identify_DryRun(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	c1 := newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0)
+	c2 := newContact(2, "marty@hillvalley.edu", "+4917622222222", 0, t0.Add(time.Hour))
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(
+		emailKey("george@hillvalley.edu"), phoneKey("+4917622222222"), emailKey("biff@hillvalley.edu"),
+	)).Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1, 2}).Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917611111111"), emailKey("marty@hillvalley.edu"))).
+		Return([]*domain.Contact{c1, c2}, nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	result, err := service.Identify(ctx, application.IdentifyRequest{
+		Identifiers: []application.IdentifierValue{
+			{Type: domain.IdentifierTypeEmail, Value: "george@hillvalley.edu"},
+			{Type: domain.IdentifierTypePhone, Value: "+4917622222222"},
+			{Type: domain.IdentifierTypeEmail, Value: "biff@hillvalley.edu"},
+		},
+		DryRun: true,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.True(t, result.Created)
+	assert.Equal(t, []*domain.LinkEvent{
+		{
+			ContactID: 2, Action: domain.LinkActionDemote,
+			BeforeLinkedID: 0, BeforePrecedence: "primary", AfterLinkedID: 1, AfterPrecedence: "secondary",
+			IdentifierType: domain.IdentifierTypePhone, IdentifierValue: "+4917622222222",
+		},
+		{
+			ContactID: 0, Action: domain.LinkActionCreate, AfterLinkedID: 1, AfterPrecedence: "secondary",
+			IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "biff@hillvalley.edu",
+		},
+	}, result.Operations)
+	assert.Equal(t, []uint{1, 2, 0}, contactIDs(result.Contacts))
+	// nothing is written: UpdateContact, CreateContact and CreateLinkEvents are not expected.
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_Identify_DryRunNewPrimary ...
+func TestService_Identify_DryRunNewPrimary(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	c1 := newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0)
+	createdAt := t0.Add(-time.Hour)
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(emailKey("george@hillvalley.edu"), emailKey("biff@hillvalley.edu"))).
+		Return([]*domain.Contact{c1}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917611111111"))).
+		Return([]*domain.Contact{c1}, nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	result, err := service.Identify(ctx, application.IdentifyRequest{
+		Identifiers: []application.IdentifierValue{
+			{Type: domain.IdentifierTypeEmail, Value: "george@hillvalley.edu"},
+			{Type: domain.IdentifierTypeEmail, Value: "biff@hillvalley.edu"},
+		},
+		CreatedAt: &createdAt,
+		DryRun:    true,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.True(t, result.Created)
+	assert.True(t, result.NewPrimary)
+	assert.Equal(t, []*domain.LinkEvent{
+		{
+			ContactID: 0, Action: domain.LinkActionCreate, AfterLinkedID: 0, AfterPrecedence: "primary",
+			IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "biff@hillvalley.edu",
+		},
+		{
+			ContactID: 1, Action: domain.LinkActionDemote,
+			BeforeLinkedID: 0, BeforePrecedence: "primary", AfterLinkedID: 0, AfterPrecedence: "secondary",
+			IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "george@hillvalley.edu",
+		},
+	}, result.Operations)
+	assert.Equal(t, []uint{1, 0}, contactIDs(result.Contacts))
+	assert.Equal(t, "primary", result.Contacts[1].LinkedPrecedence)
+	assert.Equal(t, "secondary", result.Contacts[0].LinkedPrecedence)
+	// nothing is written: UpdateContact, CreateContact and CreateLinkEvents are not expected.
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_Identify_RejectsInvalidRequests ...
+func TestService_Identify_RejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Request       application.IdentifyRequest
+		ExpectedError error
+	}{
+		{
+			Name:          "Blank email and missing phone",
+			Request:       identifyRequest(" ", ""),
+			ExpectedError: application.ErrMissingIdentifier,
+		},
+		{
+			Name:          "Invalid phone",
+			Request:       identifyRequest("", "12"),
+			ExpectedError: application.ErrInvalidIdentifier,
+		},
+		{
+			Name: "Unknown identifier type",
+			Request: application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+				{Type: "fax", Value: "+4930123456"},
+			}},
+			ExpectedError: application.ErrUnknownIdentifierType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			repoMock := new(mockObject.ContactRepositoryMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			_, err := service.Identify(context.Background(), tt.Request)
+
+			assert.ErrorIs(t, err, tt.ExpectedError)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestService_Identify_RetriesSerializationFailures ...
+func TestService_Identify_RetriesSerializationFailures(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(repository.ErrSerializationFailure).Once()
+	repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, mock.Anything).Return([]*domain.Contact{}, nil).Once()
+	repoMock.On("CreateContact", ctx, mock.Anything).
+		Return(newContact(1, "doc@hillvalley.edu", "+4917611111111", 0, t0), nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{MaxTransactionAttempts: 2})
+	result, err := service.Identify(ctx, identifyRequest("doc@hillvalley.edu", "+4917611111111"))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Contacts, 1)
+	assert.True(t, result.Created)
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_Backfill ...
+func TestService_Backfill(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	c1 := newContact(1, "doc@hillvalley.edu", "+4917611111111", 0, t0)
+	c2 := newContact(2, "emmett@hillvalley.edu", "+49 176 1111 1111", 0, t0.Add(time.Hour))
+	c2.Identifiers[1].IdentifierID = 21
+	c3 := newContact(3, "marty@hillvalley.edu", "not a phone", 0, t0.Add(2*time.Hour))
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+	repoMock.On("ListContacts", ctx, uint(0), mock.Anything).Return([]*domain.Contact{c1, c2, c3}, nil).Once()
+	repoMock.On("ListContacts", ctx, uint(3), mock.Anything).Return([]*domain.Contact{}, nil).Once()
+	repoMock.On("WithLockedTransaction", ctx, []string{"phone:+4917611111111"}).Return(nil).Once()
+	repoMock.On("UpdateIdentifierValue", ctx, uint(21), "+4917611111111").Return(nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917611111111"))).
+		Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1, 2}).Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("GetContactsByIdentifiers", ctx, keys(
+		emailKey("doc@hillvalley.edu"), emailKey("emmett@hillvalley.edu"), phoneKey("+49 176 1111 1111"),
+	)).Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+		return c.ContactID == 2 && c.LinkedID == 1 && c.LinkedPrecedence == "secondary"
+	})).Return(&domain.Contact{}, nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Once()
+
+	report, err := application.NewService(repoMock, config.IdentityConfig{}).Backfill(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &application.BackfillReport{Scanned: 3, Normalized: 1, Invalid: 1, MergedClusters: 1}, report)
+	repoMock.AssertExpectations(t)
+}
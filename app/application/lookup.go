@@ -0,0 +1,70 @@
+package application
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+// GetCluster returns the cluster of the contact with the given id, primary first. It only reads and never
+// changes the cluster, unlike Identify.
+func (s *service) GetCluster(ctx context.Context, contactID uint) ([]*domain.Contact, error) {
+	contact, err := s.repo.GetPrimaryContactFromLinkedID(ctx, contactID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting contact")
+	}
+	if contact == nil {
+		return nil, errors.WithMessagef(ErrContactNotFound, "contact %d", contactID)
+	}
+	return s.clusterOf(ctx, contact)
+}
+
+// FindCluster returns the cluster carrying the identifier, primary first. It only reads and never changes the
+// cluster, unlike Identify.
+func (s *service) FindCluster(ctx context.Context, value IdentifierValue) ([]*domain.Contact, error) {
+	identifier, err := s.identifiers.Normalize(value.Type, value.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.repo.GetContactsByIdentifiers(ctx, []domain.IdentifierKey{identifier.Key()})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by identifiers")
+	}
+	if len(matches) == 0 {
+		return nil, errors.WithMessagef(ErrContactNotFound, "no contact with the %s", identifier.Type)
+	}
+	return s.clusterOf(ctx, s.election.Elect(matches))
+}
+
+// clusterOf resolves a secondary contact to its primary and returns the primary with all of its secondaries.
+func (s *service) clusterOf(ctx context.Context, contact *domain.Contact) ([]*domain.Contact, error) {
+	primary := contact
+	if contact.LinkedID != 0 {
+		linked, err := s.repo.GetPrimaryContactFromLinkedID(ctx, contact.LinkedID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting primary contact")
+		}
+		if linked != nil {
+			primary = linked
+		}
+	}
+
+	contacts, err := s.repo.GetAllSecondaryContacts(ctx, primary.ContactID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting secondary contacts")
+	}
+	if len(contacts) == 0 {
+		return []*domain.Contact{primary}, nil
+	}
+
+	cluster := []*domain.Contact{primary}
+	for _, c := range contacts {
+		if c.ContactID != primary.ContactID {
+			cluster = append(cluster, c)
+		}
+	}
+	return cluster, nil
+}
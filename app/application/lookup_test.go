@@ -0,0 +1,138 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestService_GetCluster ...
+func TestService_GetCluster(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	primary := newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0)
+	secondary := newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))
+
+	tests := []struct {
+		Name            string
+		ContactID       uint
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedCluster []uint
+		ExpectedError   error
+	}{
+		{
+			Name:      "Secondary is resolved to its primary",
+			ContactID: 2,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetPrimaryContactFromLinkedID", ctx, uint(2)).Return(secondary, nil).Once()
+				repo.On("GetPrimaryContactFromLinkedID", ctx, uint(1)).Return(primary, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{secondary, primary}, nil).Once()
+			},
+			ExpectedCluster: []uint{1, 2},
+		},
+		{
+			Name:      "Unknown contact",
+			ContactID: 9,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetPrimaryContactFromLinkedID", ctx, uint(9)).Return((*domain.Contact)(nil), nil).Once()
+			},
+			ExpectedError: application.ErrContactNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			tt.Setup(ctx, repoMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			contacts, err := service.GetCluster(ctx, tt.ContactID)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedCluster, contactIDs(contacts))
+		})
+	}
+}
+
+// TestService_FindCluster ...
+func TestService_FindCluster(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	primary := newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0)
+	secondary := newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))
+
+	tests := []struct {
+		Name            string
+		Value           application.IdentifierValue
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedCluster []uint
+		ExpectedError   error
+	}{
+		{
+			Name:  "Phone of a secondary is matched in E.164",
+			Value: application.IdentifierValue{Type: domain.IdentifierTypePhone, Value: "+49 176 2222 2222"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917622222222"))).
+					Return([]*domain.Contact{secondary}, nil).Once()
+				repo.On("GetPrimaryContactFromLinkedID", ctx, uint(1)).Return(primary, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+			},
+			ExpectedCluster: []uint{1, 2},
+		},
+		{
+			Name:  "Unknown email",
+			Value: application.IdentifierValue{Type: domain.IdentifierTypeEmail, Value: "Marty@HillValley.edu"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("marty@hillvalley.edu"))).
+					Return([]*domain.Contact{}, nil).Once()
+			},
+			ExpectedError: application.ErrContactNotFound,
+		},
+		{
+			Name:          "Unknown identifier type",
+			Value:         application.IdentifierValue{Type: "fax", Value: "+4930123456"},
+			Setup:         func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {},
+			ExpectedError: application.ErrUnknownIdentifierType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			tt.Setup(ctx, repoMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			contacts, err := service.FindCluster(ctx, tt.Value)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedCluster, contactIDs(contacts))
+		})
+	}
+}
+
+func contactIDs(contacts []*domain.Contact) []uint {
+	ids := make([]uint, 0, len(contacts))
+	for _, c := range contacts {
+		ids = append(ids, c.ContactID)
+	}
+	return ids
+}
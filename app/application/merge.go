@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"strings"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidMergeRequest is returned when a merge request does not name two different contacts or who
+	// requested it.
+	ErrInvalidMergeRequest = errors.New("[Service][LinkIdentity] a merge needs two different contacts and a requester")
+	// ErrContactNotFound is returned when a contact a request refers to does not exist.
+	ErrContactNotFound = errors.New("[Service][LinkIdentity] contact not found")
+)
+
+// MergeRequest ...
+type MergeRequest struct {
+	// ContactIDs holds the two contacts whose clusters are merged. Either may be a primary or a secondary.
+	ContactIDs  []uint
+	RequestedBy string
+	Reason      string
+}
+
+// MergeResult ...
+type MergeResult struct {
+	// Contacts is the cluster the two contacts belong to after the merge.
+	Contacts []*domain.Contact
+	// Merged is false when both contacts already belonged to the same cluster.
+	Merged bool
+}
+
+// Merge merges the clusters of two contacts that share no identifier, for instance because a support agent knows
+// they are the same customer. The older primary is kept, exactly as if Identify had linked the clusters, and the
+// merge is recorded together with who requested it and why.
+func (s *service) Merge(ctx context.Context, req MergeRequest) (*MergeResult, error) {
+	if len(req.ContactIDs) != 2 || req.ContactIDs[0] == 0 || req.ContactIDs[1] == 0 ||
+		req.ContactIDs[0] == req.ContactIDs[1] || strings.TrimSpace(req.RequestedBy) == "" {
+		return nil, ErrInvalidMergeRequest
+	}
+
+	// the identifiers of the clusters are only known once they are read, so nothing is locked up front and the merge
+	// is kept apart from concurrent calls by the serializable isolation alone.
+	var result *MergeResult
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.merge(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *service) merge(ctx context.Context, req MergeRequest) (*MergeResult, error) {
+	first, err := s.linkedCluster(ctx, req.ContactIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range first {
+		if c.ContactID == req.ContactIDs[1] {
+			return &MergeResult{Contacts: first}, nil
+		}
+	}
+	second, err := s.linkedCluster(ctx, req.ContactIDs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	firstPrimary, secondPrimary := s.election.Elect(first), s.election.Elect(second)
+	cluster := append(append([]*domain.Contact{}, first...), second...)
+	primary, err := s.mergeCluster(ctx, cluster, linkCause{action: domain.LinkActionMerge})
+	if err != nil {
+		return nil, err
+	}
+	merged := secondPrimary
+	if primary.ContactID == secondPrimary.ContactID {
+		merged = firstPrimary
+	}
+
+	err = s.repo.CreateContactMerge(ctx, &domain.ContactMerge{
+		PrimaryContactID: primary.ContactID,
+		MergedContactID:  merged.ContactID,
+		RequestedBy:      strings.TrimSpace(req.RequestedBy),
+		Reason:           strings.TrimSpace(req.Reason),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while recording merge")
+	}
+
+	contacts, err := s.repo.GetAllSecondaryContacts(ctx, primary.ContactID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting secondary contacts")
+	}
+	return &MergeResult{Contacts: contacts, Merged: true}, nil
+}
+
+// linkedCluster returns the contact with the given id and every contact linked to it, directly or through its
+// primary. Unlike resolveCluster it does not follow shared identifiers.
+func (s *service) linkedCluster(ctx context.Context, id uint) ([]*domain.Contact, error) {
+	queried := make(map[uint]bool)
+	visited := make(map[uint]bool)
+	var cluster []*domain.Contact
+
+	queried[id] = true
+	ids := []uint{id}
+	for len(ids) > 0 {
+		linked, err := s.repo.GetContactsByLinkedIDs(ctx, ids)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting linked contacts")
+		}
+
+		var next []*domain.Contact
+		for _, c := range linked {
+			if visited[c.ContactID] {
+				continue
+			}
+			visited[c.ContactID] = true
+			cluster = append(cluster, c)
+			next = append(next, c)
+		}
+		ids = unqueriedIDs(next, queried)
+	}
+
+	if !visited[id] {
+		return nil, errors.WithMessagef(ErrContactNotFound, "contact %d", id)
+	}
+	return cluster, nil
+}
@@ -0,0 +1,114 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Merge ...
+func TestService_Merge(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name            string
+		Request         application.MergeRequest
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary uint
+		ExpectedMerged  bool
+		ExpectedError   error
+	}{
+		{
+			Name:    "Clusters are merged under the older primary",
+			Request: application.MergeRequest{ContactIDs: []uint{4, 2}, RequestedBy: "agent-7", Reason: "same customer"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+				c2 := newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))
+				c3 := newContact(3, "marty@hillvalley.edu", "", 0, t0.Add(2*time.Hour))
+				c4 := newContact(4, "", "+4917644444444", 3, t0.Add(3*time.Hour))
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{4}).Return([]*domain.Contact{c4}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return([]*domain.Contact{c3, c4}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1, c2}, nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return (c.ContactID == 3 || c.ContactID == 4) && c.LinkedID == 1 && c.LinkedPrecedence == "secondary"
+				})).Return(&domain.Contact{}, nil).Twice()
+				repo.On("CreateContactMerge", ctx, &domain.ContactMerge{
+					PrimaryContactID: 1, MergedContactID: 3, RequestedBy: "agent-7", Reason: "same customer",
+				}).Return(nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{c1, c2, c3, c4}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+			ExpectedMerged:  true,
+		},
+		{
+			Name:    "Contacts of the same cluster are left alone",
+			Request: application.MergeRequest{ContactIDs: []uint{1, 2}, RequestedBy: "agent-7"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+				c2 := newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1, c2}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+		{
+			Name:    "Unknown contact",
+			Request: application.MergeRequest{ContactIDs: []uint{1, 9}, RequestedBy: "agent-7"},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).
+					Return([]*domain.Contact{newContact(1, "george@hillvalley.edu", "", 0, t0)}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{9}).Return([]*domain.Contact{}, nil).Once()
+			},
+			ExpectedError: application.ErrContactNotFound,
+		},
+		{
+			Name:          "Missing requester",
+			Request:       application.MergeRequest{ContactIDs: []uint{1, 2}, RequestedBy: " "},
+			ExpectedError: application.ErrInvalidMergeRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.Setup != nil {
+				repoMock.On("WithTransaction", ctx).Return(nil).Once()
+				tt.Setup(ctx, repoMock)
+				repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+				repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+			}
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			result, err := service.Merge(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedMerged, result.Merged)
+			assert.Equal(t, tt.ExpectedPrimary, electedPrimary(result.Contacts))
+		})
+	}
+}
+
+func electedPrimary(contacts []*domain.Contact) uint {
+	for _, c := range contacts {
+		if c.LinkedPrecedence == "primary" {
+			return c.ContactID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxObservationFieldLength  = 255
+	maxObservationMetadataSize = 16 << 10
+)
+
+// ErrInvalidObservation is returned for an observation with a source or an order id longer than 255 characters, or
+// with metadata that is not a JSON object of at most 16 KiB.
+var ErrInvalidObservation = errors.New("[Service][LinkIdentity] invalid observation")
+
+// Observation describes where the identifiers of an identify request were seen.
+type Observation struct {
+	// Source names where the request comes from, such as a storefront or a sales channel.
+	Source string
+	// ExternalOrderID is the id of the order the identifiers came with, in the system the request comes from.
+	ExternalOrderID string
+	// OccurredAt is when the identifiers were seen. It defaults to the time the request is handled.
+	OccurredAt *time.Time
+	// Metadata is a free-form JSON object.
+	Metadata json.RawMessage
+}
+
+// Observations returns the observations of every contact currently in the cluster of the given contact, oldest
+// first.
+func (s *service) Observations(ctx context.Context, contactID uint) ([]*domain.Observation, error) {
+	cluster, err := s.linkedCluster(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(cluster))
+	for _, c := range cluster {
+		ids = append(ids, c.ContactID)
+	}
+	observations, err := s.repo.GetObservationsByContactIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting observations")
+	}
+	return observations, nil
+}
+
+// normalizeObservation trims the observation and checks its bounds. It returns nil for a missing or empty
+// observation, which is then not recorded.
+func normalizeObservation(o *Observation) (*Observation, error) {
+	if o == nil {
+		return nil, nil
+	}
+	normalized := &Observation{
+		Source:          strings.TrimSpace(o.Source),
+		ExternalOrderID: strings.TrimSpace(o.ExternalOrderID),
+		OccurredAt:      o.OccurredAt,
+	}
+	if len(normalized.Source) > maxObservationFieldLength {
+		return nil, errors.WithMessage(ErrInvalidObservation, "source is too long")
+	}
+	if len(normalized.ExternalOrderID) > maxObservationFieldLength {
+		return nil, errors.WithMessage(ErrInvalidObservation, "external_order_id is too long")
+	}
+
+	if metadata := bytes.TrimSpace(o.Metadata); len(metadata) > 0 && !bytes.Equal(metadata, []byte("null")) {
+		var object map[string]interface{}
+		if err := json.Unmarshal(metadata, &object); err != nil {
+			return nil, errors.WithMessage(ErrInvalidObservation, "metadata must be a JSON object")
+		}
+		if len(metadata) > maxObservationMetadataSize {
+			return nil, errors.WithMessage(ErrInvalidObservation, "metadata is too large")
+		}
+		normalized.Metadata = metadata
+	}
+
+	if normalized.Source == "" && normalized.ExternalOrderID == "" && normalized.OccurredAt == nil &&
+		normalized.Metadata == nil {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// recordObservation stores the observation for the contact and returns its id. It returns 0 when there is no
+// observation.
+func (s *service) recordObservation(ctx context.Context, o *Observation, contactID uint) (uint, error) {
+	if o == nil {
+		return 0, nil
+	}
+	observation := &domain.Observation{
+		ContactID:       contactID,
+		Source:          o.Source,
+		ExternalOrderID: o.ExternalOrderID,
+		OccurredAt:      time.Now(),
+		Metadata:        o.Metadata,
+		RequestID:       infrastructure.RequestID(ctx),
+	}
+	if o.OccurredAt != nil {
+		observation.OccurredAt = *o.OccurredAt
+	}
+	if err := s.repo.CreateObservation(ctx, observation); err != nil {
+		return 0, errors.Wrapf(err, "[Service][LinkIdentity] error while recording observation")
+	}
+	return observation.ObservationID, nil
+}
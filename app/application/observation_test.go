@@ -0,0 +1,110 @@
+package application_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Identify_Observation ...
+func TestService_Identify_Observation(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	observed := func(email, phone string, metadata string) application.IdentifyRequest {
+		req := identifyRequest(email, phone)
+		req.Observation = &application.Observation{
+			Source:          " web-shop ",
+			ExternalOrderID: "ORD-1985",
+			OccurredAt:      &t0,
+			Metadata:        json.RawMessage(metadata),
+		}
+		return req
+	}
+
+	tests := []struct {
+		Name          string
+		Request       application.IdentifyRequest
+		Setup         func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedError error
+	}{
+		{
+			Name:    "Observation is recorded for the contact created and the link events refer to it",
+			Request: observed("mcfly@hillvalley.edu", "+4917622222222", `{"channel":"app"}`),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "mcfly@hillvalley.edu", "+4917611111111", 0, t0)
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("mcfly@hillvalley.edu"), phoneKey("+4917622222222"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary}, nil).Once()
+				repo.On("CreateContact", ctx, mock.Anything).
+					Return(newContact(2, "mcfly@hillvalley.edu", "+4917622222222", 1, t0), nil).Once()
+				repo.On("CreateObservation", ctx, mock.MatchedBy(func(o *domain.Observation) bool {
+					return o.ContactID == 2 && o.Source == "web-shop" && o.ExternalOrderID == "ORD-1985" &&
+						o.OccurredAt.Equal(t0) && string(o.Metadata) == `{"channel":"app"}`
+				})).Run(func(args mock.Arguments) {
+					args.Get(1).(*domain.Observation).ObservationID = 7
+				}).Return(nil).Once()
+				repo.On("CreateLinkEvents", ctx, mock.MatchedBy(func(events []*domain.LinkEvent) bool {
+					return len(events) == 1 && events[0].ContactID == 2 && events[0].ObservationID == 7
+				})).Return(nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{primary}, nil).Once()
+			},
+		},
+		{
+			Name:    "Observation of a known customer is recorded for the primary",
+			Request: observed("mcfly@hillvalley.edu", "", ""),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				primary := newContact(1, "lorraine@hillvalley.edu", "+4917611111111", 0, t0)
+				secondary := newContact(2, "mcfly@hillvalley.edu", "+4917611111111", 1, t0.Add(time.Hour))
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("mcfly@hillvalley.edu"))).
+					Return([]*domain.Contact{secondary}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 1}).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("GetContactsByIdentifiers", ctx, keys(emailKey("lorraine@hillvalley.edu"), phoneKey("+4917611111111"))).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+				repo.On("CreateObservation", ctx, mock.MatchedBy(func(o *domain.Observation) bool {
+					return o.ContactID == 1 && o.Metadata == nil
+				})).Return(nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).
+					Return([]*domain.Contact{primary, secondary}, nil).Once()
+			},
+		},
+		{
+			Name:          "Metadata that is not a JSON object is rejected",
+			Request:       observed("mcfly@hillvalley.edu", "", `["app"]`),
+			ExpectedError: application.ErrInvalidObservation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.Setup != nil {
+				repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+				repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+				tt.Setup(ctx, repoMock)
+				repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+			}
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			_, err := service.Identify(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+	"github.com/link-identity/app/infrastructure/repository"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+)
+
+// Publisher publishes the domain events of the outbox to the systems downstream.
+type Publisher interface {
+	// Publish returns an error when the event could not be published; it is then published again later. An event
+	// may be published more than once, so consumers have to tolerate duplicates by its EventID.
+	Publish(ctx context.Context, event *domain.OutboxEvent) error
+}
+
+// OutboxDispatcher publishes the events of the outbox in id order. Events are only in the outbox once the
+// transaction that wrote them committed, so changes that were rolled back are never published.
+type OutboxDispatcher struct {
+	repo         repository.ContactRepository
+	publisher    Publisher
+	pollInterval time.Duration
+	batchSize    int
+}
+
+// NewOutboxDispatcher ...
+func NewOutboxDispatcher(
+	repo repository.ContactRepository,
+	publisher Publisher,
+	cfg config.OutboxConfig,
+) *OutboxDispatcher {
+	d := &OutboxDispatcher{
+		repo:         repo,
+		publisher:    publisher,
+		pollInterval: time.Duration(cfg.PollIntervalMillis) * time.Millisecond,
+		batchSize:    cfg.BatchSize,
+	}
+	if d.pollInterval <= 0 {
+		d.pollInterval = defaultOutboxPollInterval
+	}
+	if d.batchSize <= 0 {
+		d.batchSize = defaultOutboxBatchSize
+	}
+	return d
+}
+
+// Run dispatches the outbox until ctx is done. Errors are handed to onError, if set, and the batch is tried again
+// after the poll interval.
+func (d *OutboxDispatcher) Run(ctx context.Context, onError func(err error)) {
+	poll(ctx, d.pollInterval, d.batchSize, d.Dispatch, onError)
+}
+
+// Dispatch publishes the next batch of unpublished events and returns how many were published. Events are marked
+// published in the same transaction that claims them, so another dispatcher never publishes them concurrently.
+// It stops at the first event that fails to publish, leaving it and the events after it for the next batch. Every
+// event is published with the tenant it was recorded for in its context.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	var published int
+	var publishErr error
+	err := d.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		events, err := d.repo.ClaimOutboxEvents(ctx, d.batchSize)
+		if err != nil {
+			return errors.Wrapf(err, "[Service][Outbox] error while claiming events")
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, e := range events {
+			if err := d.publisher.Publish(infrastructure.WithTenantID(ctx, e.TenantID), e); err != nil {
+				publishErr = errors.Wrapf(err, "[Service][Outbox] error while publishing event %d", e.EventID)
+				break
+			}
+			ids = append(ids, e.EventID)
+		}
+		if err := d.repo.MarkOutboxEventsPublished(ctx, ids, time.Now()); err != nil {
+			return errors.Wrapf(err, "[Service][Outbox] error while marking events published")
+		}
+		published = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, publishErr
+}
+
+// poll runs batch until ctx is done. A full batch is followed by the next one straight away; otherwise poll waits
+// for the interval first.
+func poll(
+	ctx context.Context,
+	interval time.Duration,
+	batchSize int,
+	batch func(ctx context.Context) (int, error),
+	onError func(err error),
+) {
+	for {
+		n, err := batch(ctx)
+		if err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		if err == nil && n == batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// outboxEvents returns the domain events describing the link events.
+func outboxEvents(events []*domain.LinkEvent) ([]*domain.OutboxEvent, error) {
+	var outbox []*domain.OutboxEvent
+	merged := make(map[uint][]uint)
+	var primaries []uint
+	for _, e := range events {
+		typ := domain.DomainEventContactLinked
+		switch {
+		case e.Action == domain.LinkActionCreate:
+			typ = domain.DomainEventContactCreated
+		case e.BeforePrecedence == primaryPrecedence && e.AfterPrecedence == secondaryPrecedence:
+			typ = domain.DomainEventPrimaryDemoted
+			if _, ok := merged[e.AfterLinkedID]; !ok {
+				primaries = append(primaries, e.AfterLinkedID)
+			}
+			merged[e.AfterLinkedID] = append(merged[e.AfterLinkedID], e.ContactID)
+		}
+
+		payload := &domain.ContactEventPayload{
+			ContactID:        e.ContactID,
+			PrimaryContactID: primaryOf(e.ContactID, e.AfterLinkedID),
+			Precedence:       e.AfterPrecedence,
+			Action:           e.Action,
+			IdentifierType:   e.IdentifierType,
+			ObservationID:    e.ObservationID,
+		}
+		if e.Action != domain.LinkActionCreate {
+			payload.PreviousPrimaryContactID = primaryOf(e.ContactID, e.BeforeLinkedID)
+		}
+		event, err := newOutboxEvent(typ, e.ContactID, e.RequestID, payload)
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, event)
+	}
+
+	for _, primary := range primaries {
+		payload := &domain.ClustersMergedPayload{PrimaryContactID: primary, MergedPrimaryContactIDs: merged[primary]}
+		event, err := newOutboxEvent(domain.DomainEventClustersMerged, primary, events[0].RequestID, payload)
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, event)
+	}
+	return outbox, nil
+}
+
+// erasedEvents returns a DomainEventContactErased for every contact, with the primary and precedence it had.
+func erasedEvents(ctx context.Context, contacts []*domain.Contact) ([]*domain.OutboxEvent, error) {
+	outbox := make([]*domain.OutboxEvent, 0, len(contacts))
+	for _, c := range contacts {
+		payload := &domain.ContactEventPayload{
+			ContactID:        c.ContactID,
+			PrimaryContactID: primaryOf(c.ContactID, c.LinkedID),
+			Precedence:       c.LinkedPrecedence,
+			Action:           domain.LinkActionErase,
+		}
+		event, err := newOutboxEvent(domain.DomainEventContactErased, c.ContactID, infrastructure.RequestID(ctx), payload)
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, event)
+	}
+	return outbox, nil
+}
+
+func newOutboxEvent(typ string, contactID uint, requestID string, payload interface{}) (*domain.OutboxEvent, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while encoding %s event", typ)
+	}
+	return &domain.OutboxEvent{Type: typ, ContactID: contactID, Payload: string(b), RequestID: requestID}, nil
+}
+
+// primaryOf returns the primary a contact with the given link points to: linkedID, or the contact itself.
+func primaryOf(contactID, linkedID uint) uint {
+	if linkedID != 0 {
+		return linkedID
+	}
+	return contactID
+}
@@ -0,0 +1,133 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Merge_OutboxEvents ...
+func TestService_Merge_OutboxEvents(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	c1 := newContact(1, "george@hillvalley.edu", "", 0, t0)
+	c2 := newContact(2, "", "+4917622222222", 1, t0.Add(time.Hour))
+	c3 := newContact(3, "marty@hillvalley.edu", "", 0, t0.Add(2*time.Hour))
+	c4 := newContact(4, "", "+4917644444444", 3, t0.Add(3*time.Hour))
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("WithTransaction", ctx).Return(nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{4}).Return([]*domain.Contact{c4}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return([]*domain.Contact{c3, c4}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+	repoMock.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1, c2}, nil).Once()
+	repoMock.On("UpdateContact", ctx, mock.Anything).Return(&domain.Contact{}, nil).Twice()
+	repoMock.On("CreateContactMerge", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Once()
+	repoMock.On("CreateOutboxEvents", ctx, []*domain.OutboxEvent{
+		{
+			Type:      domain.DomainEventContactLinked,
+			ContactID: 4,
+			Payload: `{"contact_id":4,"primary_contact_id":1,"previous_primary_contact_id":3,` +
+				`"precedence":"secondary","action":"merge"}`,
+		},
+		{
+			Type:      domain.DomainEventPrimaryDemoted,
+			ContactID: 3,
+			Payload: `{"contact_id":3,"primary_contact_id":1,"previous_primary_contact_id":3,` +
+				`"precedence":"secondary","action":"merge"}`,
+		},
+		{
+			Type:      domain.DomainEventClustersMerged,
+			ContactID: 1,
+			Payload:   `{"primary_contact_id":1,"merged_primary_contact_ids":[3]}`,
+		},
+	}).Return(nil).Once()
+	repoMock.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{c1, c2, c3, c4}, nil).Once()
+
+	service := application.NewService(repoMock, config.IdentityConfig{})
+	_, err := service.Merge(ctx, application.MergeRequest{ContactIDs: []uint{4, 2}, RequestedBy: "agent-7"})
+
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+}
+
+// TestOutboxDispatcher_Dispatch ...
+func TestOutboxDispatcher_Dispatch(t *testing.T) {
+	events := []*domain.OutboxEvent{
+		{EventID: 1, TenantID: "shop-a", Type: domain.DomainEventContactCreated},
+		{EventID: 2, TenantID: "shop-b", Type: domain.DomainEventContactLinked},
+	}
+
+	tests := []struct {
+		Name              string
+		Setup             func(ctx context.Context, repo *mockObject.ContactRepositoryMock, pub *mockObject.PublisherMock)
+		ExpectedPublished int
+		ExpectedError     bool
+	}{
+		{
+			Name: "Claimed events are published and marked",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock, pub *mockObject.PublisherMock) {
+				repo.On("ClaimOutboxEvents", ctx, 10).Return(events, nil).Once()
+				pub.On("Publish", tenantContext("shop-a"), events[0]).Return(nil).Once()
+				pub.On("Publish", tenantContext("shop-b"), events[1]).Return(nil).Once()
+				repo.On("MarkOutboxEventsPublished", ctx, []uint{1, 2}, mock.Anything).Return(nil).Once()
+			},
+			ExpectedPublished: 2,
+		},
+		{
+			Name: "Publishing stops at the first failure and keeps the events published before",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock, pub *mockObject.PublisherMock) {
+				repo.On("ClaimOutboxEvents", ctx, 10).Return(events, nil).Once()
+				pub.On("Publish", tenantContext("shop-a"), events[0]).Return(nil).Once()
+				pub.On("Publish", tenantContext("shop-b"), events[1]).Return(errors.New("broker down")).Once()
+				repo.On("MarkOutboxEventsPublished", ctx, []uint{1}, mock.Anything).Return(nil).Once()
+			},
+			ExpectedPublished: 1,
+			ExpectedError:     true,
+		},
+		{
+			Name: "Empty outbox",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock, pub *mockObject.PublisherMock) {
+				repo.On("ClaimOutboxEvents", ctx, 10).Return([]*domain.OutboxEvent{}, nil).Once()
+				repo.On("MarkOutboxEventsPublished", ctx, []uint{}, mock.Anything).Return(nil).Once()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			publisherMock := new(mockObject.PublisherMock)
+			repoMock.On("WithTransaction", ctx).Return(nil).Once()
+			tt.Setup(ctx, repoMock, publisherMock)
+
+			dispatcher := application.NewOutboxDispatcher(repoMock, publisherMock, config.OutboxConfig{BatchSize: 10})
+			published, err := dispatcher.Dispatch(ctx)
+
+			assert.Equal(t, tt.ExpectedPublished, published)
+			assert.Equal(t, tt.ExpectedError, err != nil)
+			repoMock.AssertExpectations(t)
+			publisherMock.AssertExpectations(t)
+		})
+	}
+}
+
+// tenantContext matches the contexts carrying the tenant.
+func tenantContext(tenantID string) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		return infrastructure.TenantID(ctx) == tenantID
+	})
+}
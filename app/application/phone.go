@@ -0,0 +1,23 @@
+package application
+
+import (
+	"github.com/nyaruka/phonenumbers"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPhone is returned for phone numbers that cannot be parsed into a valid number.
+var ErrInvalidPhone = errors.New("[Service][LinkIdentity] invalid phone number")
+
+// NormalizePhone parses the phone number and formats it as E.164, so that every spelling of a number is stored and
+// matched the same way. Numbers without a country code are read as numbers of defaultRegion, an ISO 3166-1
+// alpha-2 code. With an empty defaultRegion, such numbers are invalid.
+func NormalizePhone(phone, defaultRegion string) (string, error) {
+	num, err := phonenumbers.Parse(phone, defaultRegion)
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidPhone, "%s: %s", phone, err)
+	}
+	if !phonenumbers.IsValidNumber(num) {
+		return "", errors.Wrapf(ErrInvalidPhone, "%s", phone)
+	}
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"context"
+	"strings"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidSplitRequest is returned when a split request does not name contacts that can be detached from a
+	// single cluster.
+	ErrInvalidSplitRequest = errors.New(
+		"[Service][LinkIdentity] a split needs contacts of one cluster, but not all of them",
+	)
+	// ErrSplitConflict is returned when the contacts being detached share identifiers with the contacts that stay
+	// in the cluster and the split is not forced.
+	ErrSplitConflict = errors.New("[Service][LinkIdentity] split contacts share identifiers with their cluster")
+)
+
+// SplitRequest ...
+type SplitRequest struct {
+	// ContactIDs holds the contacts detached from their cluster. They must all belong to the same cluster and
+	// form a cluster of their own afterwards.
+	ContactIDs []uint
+	// Force detaches the contacts even when they share identifiers with the contacts that stay behind. Such
+	// clusters are linked again by the next Identify call carrying one of the shared identifiers.
+	Force bool
+}
+
+// SplitResult ...
+type SplitResult struct {
+	// Contacts is the new cluster formed by the detached contacts.
+	Contacts []*domain.Contact
+	// Remaining is the cluster the contacts were detached from.
+	Remaining []*domain.Contact
+}
+
+// Split undoes a wrong link by detaching contacts from their cluster. The detached contacts form a new cluster
+// under their own primary, and the contacts left behind elect a new primary if theirs was detached. Both follow
+// the precedence rules of Identify.
+func (s *service) Split(ctx context.Context, req SplitRequest) (*SplitResult, error) {
+	if len(req.ContactIDs) == 0 {
+		return nil, ErrInvalidSplitRequest
+	}
+	for _, id := range req.ContactIDs {
+		if id == 0 {
+			return nil, ErrInvalidSplitRequest
+		}
+	}
+
+	// like a merge, a split is kept apart from concurrent calls by the serializable isolation alone.
+	var result *SplitResult
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.split(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *service) split(ctx context.Context, req SplitRequest) (*SplitResult, error) {
+	cluster, err := s.linkedCluster(ctx, req.ContactIDs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	detach := make(map[uint]bool)
+	for _, id := range req.ContactIDs {
+		detach[id] = true
+	}
+	var detached, remaining []*domain.Contact
+	for _, c := range cluster {
+		if detach[c.ContactID] {
+			detached = append(detached, c)
+			continue
+		}
+		remaining = append(remaining, c)
+	}
+	if len(detached) != len(detach) {
+		for id := range detach {
+			if _, err := s.linkedCluster(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errors.WithMessage(ErrInvalidSplitRequest, "contacts belong to different clusters")
+	}
+	if len(remaining) == 0 {
+		return nil, errors.WithMessage(ErrInvalidSplitRequest, "contacts make up the whole cluster")
+	}
+
+	if shared := sharedKeys(detached, remaining); len(shared) > 0 && !req.Force {
+		return nil, errors.WithMessagef(ErrSplitConflict, "shared identifiers %s", strings.Join(lockKeys(shared), ", "))
+	}
+
+	if _, err := s.mergeCluster(ctx, detached, linkCause{action: domain.LinkActionSplit}); err != nil {
+		return nil, err
+	}
+	if _, err := s.mergeCluster(ctx, remaining, linkCause{action: domain.LinkActionSplit}); err != nil {
+		return nil, err
+	}
+	return &SplitResult{Contacts: detached, Remaining: remaining}, nil
+}
+
+// sharedKeys returns the identifiers carried by contacts of both sets.
+func sharedKeys(a, b []*domain.Contact) []domain.IdentifierKey {
+	var shared []domain.IdentifierKey
+	seen := make(map[domain.IdentifierKey]bool)
+	for _, c := range a {
+		for _, i := range c.Identifiers {
+			if seen[i.Key()] {
+				continue
+			}
+			seen[i.Key()] = true
+			for _, other := range b {
+				if other.HasIdentifier(i.Key()) {
+					shared = append(shared, i.Key())
+					break
+				}
+			}
+		}
+	}
+	return shared
+}
@@ -0,0 +1,133 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Split ...
+func TestService_Split(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	// cluster returns a cluster of three contacts under contact 1. Contacts 1 and 2 share a phone.
+	cluster := func() []*domain.Contact {
+		return []*domain.Contact{
+			newContact(1, "george@hillvalley.edu", "+4917611111111", 0, t0),
+			newContact(2, "", "+4917611111111", 1, t0.Add(time.Hour)),
+			newContact(3, "biff@hillvalley.edu", "+4917633333333", 1, t0.Add(2*time.Hour)),
+		}
+	}
+
+	tests := []struct {
+		Name              string
+		Request           application.SplitRequest
+		Setup             func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary   uint
+		ExpectedRemaining uint
+		ExpectedError     error
+	}{
+		{
+			Name:    "Wrongly linked secondary becomes a primary",
+			Request: application.SplitRequest{ContactIDs: []uint{3}},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return(contacts[2:], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return(contacts[1:2], nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 3 && c.LinkedID == 0 && c.LinkedPrecedence == "primary"
+				})).Return(&domain.Contact{}, nil).Once()
+			},
+			ExpectedPrimary:   3,
+			ExpectedRemaining: 1,
+		},
+		{
+			Name:    "Detaching the primary re-points its secondaries",
+			Request: application.SplitRequest{ContactIDs: []uint{1, 2}, Force: false},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3}).Return(contacts[1:], nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 3 && c.LinkedID == 0 && c.LinkedPrecedence == "primary"
+				})).Return(&domain.Contact{}, nil).Once()
+			},
+			ExpectedPrimary:   1,
+			ExpectedRemaining: 3,
+		},
+		{
+			Name:    "Shared identifiers block the split",
+			Request: application.SplitRequest{ContactIDs: []uint{2}},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return(contacts[1:2], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return(contacts[2:], nil).Once()
+			},
+			ExpectedError: application.ErrSplitConflict,
+		},
+		{
+			Name:    "Forced split detaches contacts sharing identifiers",
+			Request: application.SplitRequest{ContactIDs: []uint{2}, Force: true},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return(contacts[1:2], nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{3}).Return(contacts[2:], nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 2 && c.LinkedID == 0 && c.LinkedPrecedence == "primary"
+				})).Return(&domain.Contact{}, nil).Once()
+			},
+			ExpectedPrimary:   2,
+			ExpectedRemaining: 1,
+		},
+		{
+			Name:    "Whole cluster",
+			Request: application.SplitRequest{ContactIDs: []uint{1, 2, 3}},
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				contacts := cluster()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(contacts, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3}).Return(contacts[1:], nil).Once()
+			},
+			ExpectedError: application.ErrInvalidSplitRequest,
+		},
+		{
+			Name:          "No contacts",
+			Request:       application.SplitRequest{},
+			ExpectedError: application.ErrInvalidSplitRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.Setup != nil {
+				repoMock.On("WithTransaction", ctx).Return(nil).Once()
+				tt.Setup(ctx, repoMock)
+				repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+				repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+			}
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			result, err := service.Split(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedPrimary, electedPrimary(result.Contacts))
+			assert.Equal(t, tt.ExpectedRemaining, electedPrimary(result.Remaining))
+		})
+	}
+}
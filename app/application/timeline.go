@@ -0,0 +1,214 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of timeline entries.
+const (
+	// TimelineContactCreated is a contact being inserted.
+	TimelineContactCreated = "contact_created"
+	// TimelineIdentifierFirstSeen is the first contact of the cluster carrying an identifier being inserted.
+	TimelineIdentifierFirstSeen = "identifier_first_seen"
+	// TimelineMerged is a contact moved under another primary because two clusters were merged, by an identify
+	// call or by hand.
+	TimelineMerged = "merged"
+	// TimelineRelinked is a contact relinked by a split or an erasure.
+	TimelineRelinked = "relinked"
+	// TimelineObserved is an identify call that matched the cluster without creating a contact.
+	TimelineObserved = "observed"
+	// TimelineContactUpdated is the last update of a contact stored before link events were recorded. Its link
+	// history is unknown, so the entry holds the link the contact has now.
+	TimelineContactUpdated = "contact_updated"
+)
+
+// TimelineEntry is an event in the history of a cluster. LinkedID and Precedence are the link of the contact after
+// the event, PreviousLinkedID the primary it pointed to before a relink. The observation, if any, is the identify
+// call the event came from, with the order it named.
+type TimelineEntry struct {
+	At               time.Time `json:"at"`
+	Kind             string    `json:"kind"`
+	ContactID        uint      `json:"contact_id"`
+	Action           string    `json:"action,omitempty"`
+	IdentifierType   string    `json:"identifier_type,omitempty"`
+	IdentifierValue  string    `json:"identifier_value,omitempty"`
+	LinkedID         uint      `json:"linked_id,omitempty"`
+	PreviousLinkedID uint      `json:"previous_linked_id,omitempty"`
+	Precedence       string    `json:"precedence,omitempty"`
+	EventID          uint      `json:"event_id,omitempty"`
+	ObservationID    uint      `json:"observation_id,omitempty"`
+	Source           string    `json:"source,omitempty"`
+	ExternalOrderID  string    `json:"external_order_id,omitempty"`
+}
+
+// Timeline returns up to limit entries of the history of the cluster of the given contact, oldest first, skipping
+// the first offset entries. The history is built from the contacts of the cluster, their link events and the
+// observations recorded for them.
+func (s *service) Timeline(ctx context.Context, contactID uint, offset, limit int) ([]*TimelineEntry, error) {
+	cluster, err := s.linkedCluster(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(cluster))
+	for _, c := range cluster {
+		ids = append(ids, c.ContactID)
+	}
+	events, err := s.repo.GetLinkEventsByContactIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting link events")
+	}
+	observations, err := s.repo.GetObservationsByContactIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while getting observations")
+	}
+
+	entries := timelineEntries(cluster, events, observations)
+	start := min(max(offset, 0), len(entries))
+	end := min(start+pageSize(limit), len(entries))
+	return entries[start:end], nil
+}
+
+// timelineEntries returns the entries of the history of the cluster in chronological order. Entries at the same
+// time keep the order of the contacts by creation, then of the link events, then of the observations.
+func timelineEntries(
+	cluster []*domain.Contact,
+	events []*domain.LinkEvent,
+	observations []*domain.Observation,
+) []*TimelineEntry {
+	contacts := append([]*domain.Contact(nil), cluster...)
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return isOlder(contacts[i], contacts[j])
+	})
+
+	observationsByID := make(map[uint]*domain.Observation, len(observations))
+	for _, o := range observations {
+		observationsByID[o.ObservationID] = o
+	}
+	createdBy := make(map[uint]*domain.LinkEvent)
+	relinked := make(map[uint]bool)
+	for _, e := range events {
+		if e.Action == domain.LinkActionCreate {
+			createdBy[e.ContactID] = e
+		} else {
+			relinked[e.ContactID] = true
+		}
+	}
+	// observations a link event refers to are told by the entry of the event.
+	told := make(map[uint]bool)
+	for _, e := range events {
+		told[e.ObservationID] = true
+	}
+
+	var entries []*TimelineEntry
+	seen := make(map[domain.IdentifierKey]bool)
+	for _, c := range contacts {
+		created := &TimelineEntry{
+			At:         timeOf(c.CreatedAt),
+			Kind:       TimelineContactCreated,
+			ContactID:  c.ContactID,
+			LinkedID:   c.LinkedID,
+			Precedence: c.LinkedPrecedence,
+		}
+		if e, ok := createdBy[c.ContactID]; ok {
+			created.LinkedID = e.AfterLinkedID
+			created.Precedence = e.AfterPrecedence
+			created.EventID = e.EventID
+			withObservation(created, observationsByID[e.ObservationID])
+		}
+		entries = append(entries, created)
+
+		for _, i := range c.Identifiers {
+			if seen[i.Key()] {
+				continue
+			}
+			seen[i.Key()] = true
+			entry := &TimelineEntry{
+				At:              created.At,
+				Kind:            TimelineIdentifierFirstSeen,
+				ContactID:       c.ContactID,
+				IdentifierType:  i.Type,
+				IdentifierValue: i.DisplayValue(),
+			}
+			withObservation(entry, observationsByID[created.ObservationID])
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, e := range events {
+		if e.Action == domain.LinkActionCreate {
+			continue
+		}
+		kind := TimelineRelinked
+		switch e.Action {
+		case domain.LinkActionLink, domain.LinkActionDemote, domain.LinkActionMerge:
+			kind = TimelineMerged
+		}
+		entry := &TimelineEntry{
+			At:               e.CreatedAt,
+			Kind:             kind,
+			ContactID:        e.ContactID,
+			Action:           e.Action,
+			IdentifierType:   e.IdentifierType,
+			IdentifierValue:  e.IdentifierValue,
+			LinkedID:         e.AfterLinkedID,
+			PreviousLinkedID: e.BeforeLinkedID,
+			Precedence:       e.AfterPrecedence,
+			EventID:          e.EventID,
+		}
+		withObservation(entry, observationsByID[e.ObservationID])
+		entries = append(entries, entry)
+	}
+
+	for _, o := range observations {
+		if told[o.ObservationID] {
+			continue
+		}
+		entry := &TimelineEntry{At: o.OccurredAt, Kind: TimelineObserved, ContactID: o.ContactID}
+		withObservation(entry, o)
+		entries = append(entries, entry)
+	}
+
+	for _, c := range contacts {
+		_, created := createdBy[c.ContactID]
+		if created || relinked[c.ContactID] || c.UpdatedAt == nil || !c.UpdatedAt.After(timeOf(c.CreatedAt)) {
+			continue
+		}
+		entries = append(entries, &TimelineEntry{
+			At:         *c.UpdatedAt,
+			Kind:       TimelineContactUpdated,
+			ContactID:  c.ContactID,
+			LinkedID:   c.LinkedID,
+			Precedence: c.LinkedPrecedence,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return entries
+}
+
+// withObservation copies the observation, if there is one, into the entry.
+func withObservation(entry *TimelineEntry, o *domain.Observation) {
+	if o == nil {
+		return
+	}
+	entry.ObservationID = o.ObservationID
+	entry.Source = o.Source
+	entry.ExternalOrderID = o.ExternalOrderID
+}
+
+// timeOf returns the time t points to, or the zero time.
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
@@ -0,0 +1,150 @@
+package application_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestService_Timeline ...
+func TestService_Timeline(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time {
+		return t0.Add(time.Duration(hours) * time.Hour)
+	}
+
+	primary := newContact(1, "Doc@HillValley.edu", "+4917611111111", 0, at(0))
+	secondary := newContact(2, "marty@hillvalley.edu", "+4917611111111", 1, at(1))
+	// a contact stored before link events were recorded, relinked since.
+	legacy := newContact(3, "biff@hillvalley.edu", "", 1, at(2))
+	legacy.Identifiers[0].RawValue = ""
+	updatedAt := at(3)
+	legacy.UpdatedAt = &updatedAt
+	demoted := newContact(4, "", "+4917644444444", 1, at(5))
+	demoted.Identifiers[0].RawValue = "+49 176 4444 4444"
+	cluster := []*domain.Contact{primary, secondary, legacy, demoted}
+
+	events := []*domain.LinkEvent{
+		{EventID: 10, ContactID: 1, Action: domain.LinkActionCreate, AfterPrecedence: "primary", ObservationID: 20,
+			CreatedAt: at(0)},
+		{EventID: 11, ContactID: 2, Action: domain.LinkActionCreate, AfterLinkedID: 1, AfterPrecedence: "secondary",
+			ObservationID: 21, CreatedAt: at(1)},
+		{EventID: 13, ContactID: 4, Action: domain.LinkActionCreate, AfterPrecedence: "primary", CreatedAt: at(5)},
+		{EventID: 14, ContactID: 4, Action: domain.LinkActionDemote, BeforePrecedence: "primary", AfterLinkedID: 1,
+			AfterPrecedence: "secondary", IdentifierType: domain.IdentifierTypeEmail,
+			IdentifierValue: "marty@hillvalley.edu", CreatedAt: at(6)},
+	}
+	observations := []*domain.Observation{
+		{ObservationID: 20, ContactID: 1, Source: "web-shop", ExternalOrderID: "ORD-1", OccurredAt: at(0)},
+		{ObservationID: 21, ContactID: 2, Source: "web-shop", ExternalOrderID: "ORD-2", OccurredAt: at(1)},
+		{ObservationID: 22, ContactID: 1, Source: "pos", ExternalOrderID: "ORD-3", OccurredAt: at(4)},
+	}
+	timeline := []string{
+		"0h contact_created 1 ORD-1",
+		"0h identifier_first_seen 1 email:Doc@HillValley.edu ORD-1",
+		"0h identifier_first_seen 1 phone:+4917611111111 ORD-1",
+		"1h contact_created 2 ORD-2",
+		"1h identifier_first_seen 2 email:marty@hillvalley.edu ORD-2",
+		"2h contact_created 3",
+		"2h identifier_first_seen 3 email:biff@hillvalley.edu",
+		"3h contact_updated 3",
+		"4h observed 1 ORD-3",
+		"5h contact_created 4",
+		"5h identifier_first_seen 4 phone:+49 176 4444 4444",
+		"6h merged 4 email:marty@hillvalley.edu",
+	}
+
+	tests := []struct {
+		Name             string
+		ContactID        uint
+		Offset           int
+		Limit            int
+		Setup            func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedTimeline []string
+		ExpectedError    error
+	}{
+		{
+			Name:      "History of the cluster in chronological order",
+			ContactID: 1,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(cluster, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3, 4}).Return(cluster, nil).Once()
+				repo.On("GetLinkEventsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(events, nil).Once()
+				repo.On("GetObservationsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(observations, nil).Once()
+			},
+			ExpectedTimeline: timeline,
+		},
+		{
+			Name:      "Page of the history",
+			ContactID: 1,
+			Offset:    10,
+			Limit:     5,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(cluster, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3, 4}).Return(cluster, nil).Once()
+				repo.On("GetLinkEventsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(events, nil).Once()
+				repo.On("GetObservationsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(observations, nil).Once()
+			},
+			ExpectedTimeline: timeline[10:],
+		},
+		{
+			Name:      "Offset past the end of the history",
+			ContactID: 1,
+			Offset:    20,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return(cluster, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2, 3, 4}).Return(cluster, nil).Once()
+				repo.On("GetLinkEventsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(events, nil).Once()
+				repo.On("GetObservationsByContactIDs", ctx, []uint{1, 2, 3, 4}).Return(observations, nil).Once()
+			},
+			ExpectedTimeline: []string{},
+		},
+		{
+			Name:      "Unknown contact",
+			ContactID: 9,
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{9}).Return([]*domain.Contact{}, nil).Once()
+			},
+			ExpectedError: application.ErrContactNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			tt.Setup(ctx, repoMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			entries, err := service.Timeline(ctx, tt.ContactID, tt.Offset, tt.Limit)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			described := []string{}
+			for _, e := range entries {
+				description := fmt.Sprintf("%dh %s %d", int(e.At.Sub(t0).Hours()), e.Kind, e.ContactID)
+				if e.IdentifierValue != "" {
+					description += " " + e.IdentifierType + ":" + e.IdentifierValue
+				}
+				if e.ExternalOrderID != "" {
+					description += " " + e.ExternalOrderID
+				}
+				described = append(described, description)
+			}
+			assert.Equal(t, tt.ExpectedTimeline, described)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+)
+
+// ErrIdentifierNotFound is returned when a contact does not carry the identifier of a request.
+var ErrIdentifierNotFound = errors.New("[Service][LinkIdentity] identifier not found")
+
+// VerifyRequest ...
+type VerifyRequest struct {
+	ContactID  uint
+	Identifier IdentifierValue
+}
+
+// VerifyIdentifier records that the customer of the contact proved to own the identifier. In the
+// config.LinkingModeVerifiedOnly linking mode, the identifier may then merge the cluster of the contact with other
+// clusters carrying it verified, which happens on the next /identify carrying it.
+func (s *service) VerifyIdentifier(ctx context.Context, req VerifyRequest) error {
+	identifier, err := s.identifiers.Normalize(req.Identifier.Type, req.Identifier.Value)
+	if err != nil {
+		return err
+	}
+	found, err := s.repo.VerifyIdentifier(ctx, req.ContactID, identifier.Key(), time.Now())
+	if err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while verifying identifier")
+	}
+	if !found {
+		return errors.WithMessagef(ErrIdentifierNotFound, "contact %d, %s", req.ContactID, identifier.Type)
+	}
+	return nil
+}
+
+// resolve returns the contacts the identifiers link to, as decided by the linking mode, and the identifiers that
+// were not followed.
+func (s *service) resolve(
+	ctx context.Context,
+	identifiers []*domain.Identifier,
+) ([]*domain.Contact, *guarded, error) {
+	if s.cfg.LinkingMode == config.LinkingModeVerifiedOnly {
+		return s.resolveVerifiedCluster(ctx, identifiers)
+	}
+	return s.resolveCluster(ctx, identifierKeys(identifiers))
+}
+
+// resolveVerifiedCluster is resolveCluster for the config.LinkingModeVerifiedOnly linking mode. It only follows
+// the identifiers themselves and the links of the clusters they match, never the other identifiers of those
+// clusters. Every cluster matched by a verified identifier, verified on both the request and a contact of the
+// cluster, is returned to be merged. Without such a cluster, the identifiers only attach to one of the clusters
+// they match, the one whose contact the election policy elects among them; the others are left alone.
+func (s *service) resolveVerifiedCluster(
+	ctx context.Context,
+	identifiers []*domain.Identifier,
+) ([]*domain.Contact, *guarded, error) {
+	g, err := s.newGuarded(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := s.guardKeys(ctx, unseenKeys(identifierKeys(identifiers), make(map[domain.IdentifierKey]bool)), g)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, g, nil
+	}
+
+	matches, err := s.repo.GetContactsByIdentifiers(ctx, keys)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "[Service][LinkIdentity] error from repo while getting contacts by identifiers")
+	}
+
+	verified := verifiedKeys(identifiers)
+	visited := make(map[uint]bool)
+	var merged, unverified []*domain.Contact
+	var clusters [][]*domain.Contact
+	for _, m := range matches {
+		if visited[m.ContactID] {
+			continue
+		}
+		cluster, err := s.linkedCluster(ctx, m.ContactID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range cluster {
+			visited[c.ContactID] = true
+		}
+		if carriesVerified(cluster, verified) {
+			merged = append(merged, cluster...)
+			continue
+		}
+		unverified = append(unverified, cluster...)
+		clusters = append(clusters, cluster)
+	}
+	if len(merged) > 0 || len(clusters) == 0 {
+		return merged, g, nil
+	}
+
+	elected := s.election.Elect(unverified)
+	for _, cluster := range clusters {
+		for _, c := range cluster {
+			if c == elected {
+				return cluster, g, nil
+			}
+		}
+	}
+	return clusters[0], g, nil
+}
+
+// verifyMatched marks the identifiers of the cluster that the request carries verified, and that are not verified
+// yet, verified as of the request. It returns them to be stored.
+func verifyMatched(cluster []*domain.Contact, identifiers []*domain.Identifier) []*domain.Identifier {
+	verifiedAt := make(map[domain.IdentifierKey]*time.Time)
+	for _, i := range identifiers {
+		if i.VerifiedAt != nil {
+			verifiedAt[i.Key()] = i.VerifiedAt
+		}
+	}
+	var matched []*domain.Identifier
+	for _, c := range cluster {
+		for _, i := range c.Identifiers {
+			if at, ok := verifiedAt[i.Key()]; ok && i.VerifiedAt == nil {
+				i.VerifiedAt = at
+				matched = append(matched, i)
+			}
+		}
+	}
+	return matched
+}
+
+// storeVerifications stores the verification of the identifiers, as VerifyIdentifier does.
+func (s *service) storeVerifications(ctx context.Context, identifiers []*domain.Identifier) error {
+	for _, i := range identifiers {
+		if _, err := s.repo.VerifyIdentifier(ctx, i.ContactID, i.Key(), *i.VerifiedAt); err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while verifying identifier")
+		}
+	}
+	return nil
+}
+
+// verifiedKeys returns the keys of the verified identifiers.
+func verifiedKeys(identifiers []*domain.Identifier) map[domain.IdentifierKey]bool {
+	keys := make(map[domain.IdentifierKey]bool)
+	for _, i := range identifiers {
+		if i.VerifiedAt != nil {
+			keys[i.Key()] = true
+		}
+	}
+	return keys
+}
+
+// carriesVerified reports whether a contact of the cluster carries one of the keys verified.
+func carriesVerified(cluster []*domain.Contact, keys map[domain.IdentifierKey]bool) bool {
+	for _, c := range cluster {
+		for _, i := range c.Identifiers {
+			if i.VerifiedAt != nil && keys[i.Key()] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestService_Identify_VerifiedOnly ...
+func TestService_Identify_VerifiedOnly(t *testing.T) {
+	t0 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	verified := func(c *domain.Contact) *domain.Contact {
+		c.Identifiers[0].VerifiedAt = &t0
+		return c
+	}
+	request := func(emailVerified, phoneVerified bool) application.IdentifyRequest {
+		return application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+			{Type: domain.IdentifierTypeEmail, Value: "doc@hillvalley.edu", Verified: emailVerified},
+			{Type: domain.IdentifierTypePhone, Value: "+4917611111111", Verified: phoneVerified},
+		}}
+	}
+	requested := keys(emailKey("doc@hillvalley.edu"), phoneKey("+4917611111111"))
+
+	tests := []struct {
+		Name            string
+		Request         application.IdentifyRequest
+		Setup           func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedPrimary uint
+		ExpectedCreated bool
+	}{
+		{
+			Name:    "Verified match attaches a secondary and leaves the unverified match alone",
+			Request: request(true, false),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := verified(newContact(1, "doc@hillvalley.edu", "", 0, t0))
+				c2 := newContact(2, "", "+4917611111111", 0, t0.Add(time.Hour))
+				repo.On("GetContactsByIdentifiers", ctx, requested).Return([]*domain.Contact{c1, c2}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedID == 1 && c.Identifiers[0].VerifiedAt != nil && c.Identifiers[1].VerifiedAt == nil
+				})).Return(newContact(3, "doc@hillvalley.edu", "+4917611111111", 1, t0), nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{c1}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+			ExpectedCreated: true,
+		},
+		{
+			Name:    "Verified matches merge their clusters",
+			Request: request(true, true),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := verified(newContact(1, "doc@hillvalley.edu", "", 0, t0))
+				c2 := verified(newContact(2, "", "+4917611111111", 0, t0.Add(time.Hour)))
+				repo.On("GetContactsByIdentifiers", ctx, requested).Return([]*domain.Contact{c1, c2}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+				repo.On("UpdateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.ContactID == 2 && c.LinkedID == 1
+				})).Return(c2, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{c1, c2}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+		{
+			Name:    "Unverified matches only attach a secondary to the elected cluster",
+			Request: request(false, false),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "doc@hillvalley.edu", "", 0, t0.Add(time.Hour))
+				c2 := verified(newContact(2, "", "+4917611111111", 0, t0))
+				repo.On("GetContactsByIdentifiers", ctx, requested).Return([]*domain.Contact{c1, c2}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{2}).Return([]*domain.Contact{c2}, nil).Once()
+				repo.On("CreateContact", ctx, mock.MatchedBy(func(c *domain.Contact) bool {
+					return c.LinkedID == 2
+				})).Return(newContact(3, "doc@hillvalley.edu", "+4917611111111", 2, t0), nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(2)).Return([]*domain.Contact{c2}, nil).Once()
+			},
+			ExpectedPrimary: 2,
+			ExpectedCreated: true,
+		},
+		{
+			Name:    "Verified identifier a known contact carries unverified is stored as verified",
+			Request: request(true, false),
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				c1 := newContact(1, "doc@hillvalley.edu", "+4917611111111", 0, t0)
+				repo.On("GetContactsByIdentifiers", ctx, requested).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("GetContactsByLinkedIDs", ctx, []uint{1}).Return([]*domain.Contact{c1}, nil).Once()
+				repo.On("VerifyIdentifier", ctx, uint(1), emailKey("doc@hillvalley.edu"), mock.Anything).Return(true, nil).Once()
+				repo.On("GetAllSecondaryContacts", ctx, uint(1)).Return([]*domain.Contact{c1}, nil).Once()
+			},
+			ExpectedPrimary: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("ListDenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{}, nil).Maybe()
+			repoMock.On("WithLockedTransaction", ctx, mock.Anything).Return(nil).Once()
+			tt.Setup(ctx, repoMock)
+			repoMock.On("CreateLinkEvents", ctx, mock.Anything).Return(nil).Maybe()
+			repoMock.On("CreateOutboxEvents", ctx, mock.Anything).Return(nil).Maybe()
+
+			service := application.NewService(repoMock, config.IdentityConfig{LinkingMode: config.LinkingModeVerifiedOnly})
+			result, err := service.Identify(ctx, tt.Request)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedPrimary, result.Contacts[0].ContactID)
+			assert.Equal(t, tt.ExpectedCreated, result.Created)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestService_VerifyIdentifier ...
+func TestService_VerifyIdentifier(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Value         string
+		Setup         func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedError error
+	}{
+		{
+			Name:  "Identifier of the contact is verified",
+			Value: "+49 176 1111 1111",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("VerifyIdentifier", ctx, uint(1), phoneKey("+4917611111111"), mock.Anything).Return(true, nil).Once()
+			},
+		},
+		{
+			Name:  "Identifier the contact does not carry is not found",
+			Value: "+4917611111111",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("VerifyIdentifier", ctx, uint(1), phoneKey("+4917611111111"), mock.Anything).Return(false, nil).Once()
+			},
+			ExpectedError: application.ErrIdentifierNotFound,
+		},
+		{
+			Name:          "Invalid identifier is rejected",
+			Value:         "12",
+			Setup:         func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {},
+			ExpectedError: application.ErrInvalidIdentifier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			tt.Setup(ctx, repoMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{DefaultPhoneRegion: "DE"})
+			err := service.VerifyIdentifier(ctx, application.VerifyRequest{
+				ContactID:  1,
+				Identifier: application.IdentifierValue{Type: domain.IdentifierTypePhone, Value: tt.Value},
+			})
+
+			assert.ErrorIs(t, err, tt.ExpectedError)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,557 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+	"github.com/link-identity/app/infrastructure/repository"
+
+	"github.com/pkg/errors"
+)
+
+// Headers of a webhook delivery.
+const (
+	// HeaderWebhookSignature holds "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot and the body, keyed
+	// with the secret of the subscription. See SignWebhook.
+	HeaderWebhookSignature = "X-Webhook-Signature"
+	// HeaderWebhookTimestamp holds the Unix time the attempt was signed at.
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookEventID   = "X-Webhook-Event-ID"
+	HeaderWebhookEventType = "X-Webhook-Event-Type"
+)
+
+const (
+	defaultWebhookMaxAttempts  = 8
+	defaultWebhookBackoff      = time.Second
+	defaultWebhookMaxBackoff   = time.Hour
+	defaultWebhookTimeout      = 10 * time.Second
+	defaultWebhookPollInterval = time.Second
+	defaultWebhookBatchSize    = 50
+
+	// maxWebhookErrorLength bounds the response body kept as the error of a failed attempt.
+	maxWebhookErrorLength = 512
+)
+
+var (
+	// ErrInvalidWebhookSubscription is returned for a subscription without an https URL, with a URL of a loopback,
+	// private or link-local address, or with an unknown event type.
+	ErrInvalidWebhookSubscription = errors.New("[Service][LinkIdentity] invalid webhook subscription")
+	// ErrWebhookSubscriptionNotFound is returned when a webhook subscription a request refers to does not exist.
+	ErrWebhookSubscriptionNotFound = errors.New("[Service][LinkIdentity] webhook subscription not found")
+	// ErrWebhookDeadLetterNotFound is returned when a dead letter a request refers to does not exist.
+	ErrWebhookDeadLetterNotFound = errors.New("[Service][LinkIdentity] webhook dead letter not found")
+)
+
+// domainEventTypes are the event types a webhook can subscribe to.
+var domainEventTypes = []string{
+	domain.DomainEventContactCreated,
+	domain.DomainEventContactLinked,
+	domain.DomainEventPrimaryDemoted,
+	domain.DomainEventClustersMerged,
+	domain.DomainEventContactErased,
+}
+
+// WebhookSubscriptionRequest ...
+type WebhookSubscriptionRequest struct {
+	URL string
+	// Secret signs the deliveries. A random one is generated when it is empty.
+	Secret string
+	// EventTypes are the domain event types to deliver. Every type is delivered when it is empty.
+	EventTypes []string
+}
+
+// WebhookEvent is the body of a webhook delivery.
+type WebhookEvent struct {
+	EventID   uint            `json:"event_id"`
+	TenantID  string          `json:"tenant_id"`
+	Type      string          `json:"type"`
+	ContactID uint            `json:"contact_id"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// SignWebhook returns the HeaderWebhookSignature of a delivery body signed at the timestamp. Receivers compute it
+// the same way to check a delivery.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhookSubscription registers a URL the domain events are delivered to from then on.
+func (s *service) CreateWebhookSubscription(
+	ctx context.Context,
+	req WebhookSubscriptionRequest,
+) (*domain.WebhookSubscription, error) {
+	u, err := url.ParseRequestURI(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.WithMessagef(ErrInvalidWebhookSubscription, "url %q", req.URL)
+	}
+	if err := checkWebhookURL(u, s.webhookHosts); err != nil {
+		return nil, err
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(domainEventTypes, t) {
+			return nil, errors.WithMessagef(ErrInvalidWebhookSubscription, "event type %q", t)
+		}
+	}
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while generating webhook secret")
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	subscription := &domain.WebhookSubscription{
+		URL:        u.String(),
+		Secret:     secret,
+		EventTypes: strings.Join(req.EventTypes, ","),
+	}
+	if err := s.repo.CreateWebhookSubscription(ctx, subscription); err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while creating webhook subscription")
+	}
+	return subscription, nil
+}
+
+// checkWebhookURL refuses the URLs a webhook could reach internal services with: plain http, localhost and the
+// loopback, private, link-local and unspecified addresses. The allowed hosts are exempt. Host names are not
+// resolved here, as what they resolve to may change; the deliverer checks the addresses it connects to.
+func checkWebhookURL(u *url.URL, allowedHosts []string) error {
+	host := strings.ToLower(u.Hostname())
+	if webhookHostAllowed(host, allowedHosts) {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return errors.WithMessagef(ErrInvalidWebhookSubscription, "url %q is not https", u)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.WithMessagef(ErrInvalidWebhookSubscription, "url %q targets an internal address", u)
+	}
+	if ip := net.ParseIP(host); ip != nil && internalIP(ip) {
+		return errors.WithMessagef(ErrInvalidWebhookSubscription, "url %q targets an internal address", u)
+	}
+	return nil
+}
+
+func webhookHostAllowed(host string, allowedHosts []string) bool {
+	for _, allowed := range allowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalIP reports whether the address is one a webhook must not reach unless its host is allowed.
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// WebhookSubscriptions returns every webhook subscription in id order.
+func (s *service) WebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	subscriptions, err := s.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while listing webhook subscriptions")
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription removes the webhook subscription with the given id and its pending deliveries.
+func (s *service) DeleteWebhookSubscription(ctx context.Context, id uint) error {
+	var deleted bool
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = s.repo.DeleteWebhookSubscription(ctx, id)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[Service][LinkIdentity] error while deleting webhook subscription")
+	}
+	if !deleted {
+		return errors.WithMessagef(ErrWebhookSubscriptionNotFound, "subscription %d", id)
+	}
+	return nil
+}
+
+// WebhookDeadLetters returns up to limit dead letters with an id greater than afterID, in id order. A limit that
+// is not positive returns the default page size; larger limits are capped.
+func (s *service) WebhookDeadLetters(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.WebhookDeadLetter, error) {
+	letters, err := s.repo.ListWebhookDeadLetters(ctx, afterID, pageSize(limit))
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Service][LinkIdentity] error while listing webhook dead letters")
+	}
+	return letters, nil
+}
+
+// ReplayWebhookDeadLetter delivers the dead letter with the given id again, as a new delivery with every attempt
+// left.
+func (s *service) ReplayWebhookDeadLetter(ctx context.Context, id uint) (*domain.WebhookDelivery, error) {
+	var delivery *domain.WebhookDelivery
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		letter, err := s.repo.GetWebhookDeadLetter(ctx, id)
+		if err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while getting webhook dead letter")
+		}
+		if letter == nil {
+			return errors.WithMessagef(ErrWebhookDeadLetterNotFound, "dead letter %d", id)
+		}
+		subscriptions, err := s.repo.ListWebhookSubscriptions(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while listing webhook subscriptions")
+		}
+		if findSubscription(subscriptions, letter.SubscriptionID) == nil {
+			return errors.WithMessagef(ErrWebhookSubscriptionNotFound, "subscription %d", letter.SubscriptionID)
+		}
+
+		now := time.Now()
+		delivery = &domain.WebhookDelivery{
+			SubscriptionID: letter.SubscriptionID,
+			EventID:        letter.EventID,
+			EventType:      letter.EventType,
+			Payload:        letter.Payload,
+			NextAttemptAt:  now,
+		}
+		if err := s.repo.CreateWebhookDeliveries(ctx, []*domain.WebhookDelivery{delivery}); err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while creating webhook delivery")
+		}
+		if err := s.repo.MarkWebhookDeadLetterReplayed(ctx, id, now); err != nil {
+			return errors.Wrapf(err, "[Service][LinkIdentity] error while marking webhook dead letter replayed")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// WebhookPublisher publishes outbox events by queueing a delivery for every webhook subscription receiving them.
+// The deliveries are written in the transaction of the dispatcher, so an event is queued exactly once.
+type WebhookPublisher struct {
+	repo repository.ContactRepository
+}
+
+// NewWebhookPublisher ...
+func NewWebhookPublisher(repo repository.ContactRepository) *WebhookPublisher {
+	return &WebhookPublisher{repo: repo}
+}
+
+// Publish ...
+func (p *WebhookPublisher) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	subscriptions, err := p.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "[Service][Webhook] error while listing subscriptions")
+	}
+	body, err := json.Marshal(&WebhookEvent{
+		EventID:   event.EventID,
+		TenantID:  event.TenantID,
+		Type:      event.Type,
+		ContactID: event.ContactID,
+		RequestID: event.RequestID,
+		CreatedAt: event.CreatedAt,
+		Payload:   json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "[Service][Webhook] error while encoding event %d", event.EventID)
+	}
+
+	now := time.Now()
+	var deliveries []*domain.WebhookDelivery
+	for _, sub := range subscriptions {
+		if !sub.Receives(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &domain.WebhookDelivery{
+			SubscriptionID: sub.SubscriptionID,
+			EventID:        event.EventID,
+			EventType:      event.Type,
+			Payload:        string(body),
+			NextAttemptAt:  now,
+		})
+	}
+	if err := p.repo.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return errors.Wrapf(err, "[Service][Webhook] error while queueing deliveries")
+	}
+	return nil
+}
+
+// Publishers publishes every event with each of the publishers in turn, stopping at the first that fails.
+type Publishers []Publisher
+
+// Publish ...
+func (ps Publishers) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	for _, p := range ps {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookDeliverer sends the queued webhook deliveries. A delivery succeeds on a 2xx response. A failed attempt
+// is tried again after a backoff doubling with every attempt, and the delivery is moved to the dead letters once
+// it used up its attempts.
+type WebhookDeliverer struct {
+	repo         repository.ContactRepository
+	client       *http.Client
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	batchSize    int
+	// lease is how long a claimed batch is left to the deliverer before it can be claimed again.
+	lease time.Duration
+}
+
+// NewWebhookDeliverer returns a deliverer sending with the client or, when it is nil, with a client bounded by the
+// configured timeout that only connects to internal addresses for the allowed hosts.
+func NewWebhookDeliverer(
+	repo repository.ContactRepository,
+	client *http.Client,
+	cfg config.WebhookConfig,
+) *WebhookDeliverer {
+	d := &WebhookDeliverer{
+		repo:         repo,
+		client:       client,
+		maxAttempts:  cfg.MaxAttempts,
+		backoff:      time.Duration(cfg.BackoffMillis) * time.Millisecond,
+		maxBackoff:   time.Duration(cfg.MaxBackoffMillis) * time.Millisecond,
+		pollInterval: time.Duration(cfg.PollIntervalMillis) * time.Millisecond,
+		batchSize:    cfg.BatchSize,
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultWebhookMaxAttempts
+	}
+	if d.backoff <= 0 {
+		d.backoff = defaultWebhookBackoff
+	}
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = defaultWebhookMaxBackoff
+	}
+	if d.pollInterval <= 0 {
+		d.pollInterval = defaultWebhookPollInterval
+	}
+	if d.batchSize <= 0 {
+		d.batchSize = defaultWebhookBatchSize
+	}
+	if d.client == nil {
+		timeout := time.Duration(cfg.TimeoutMillis) * time.Millisecond
+		if timeout <= 0 {
+			timeout = defaultWebhookTimeout
+		}
+		d.client = newWebhookClient(timeout, cfg.AllowedHosts)
+	}
+	// the deliveries of a batch are sent one after the other, each within the timeout of the client.
+	timeout := d.client.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	d.lease = time.Duration(d.batchSize) * timeout
+	return d
+}
+
+// newWebhookClient returns a client that refuses to connect to the addresses internalIP reports, except for the
+// allowed hosts. It checks the addresses host names resolve to when it connects, uses no proxy, which would connect
+// for it, and follows no redirects, which could lead it back inside.
+func newWebhookClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	allowed := &net.Dialer{Timeout: timeout}
+	guarded := &net.Dialer{Timeout: timeout, Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+			return errors.Errorf("[Service][Webhook] refusing to connect to the internal address %s", host)
+		}
+		return nil
+	}}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && webhookHostAllowed(host, allowedHosts) {
+			return allowed.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run delivers the due deliveries until ctx is done. Errors are handed to onError, if set.
+func (d *WebhookDeliverer) Run(ctx context.Context, onError func(err error)) {
+	poll(ctx, d.pollInterval, d.batchSize, d.Deliver, onError)
+}
+
+// Deliver attempts the next batch of due deliveries and returns how many were attempted. The batch is claimed and
+// leased in a transaction of its own, so that no transaction is held open while the receivers answer, and the
+// outcome of every attempt is stored in another. A delivery whose outcome is never stored, for instance because
+// the deliverer stopped, is attempted again once its lease runs out.
+func (d *WebhookDeliverer) Deliver(ctx context.Context) (int, error) {
+	var deliveries []*domain.WebhookDelivery
+	err := d.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		var err error
+		deliveries, err = d.repo.ClaimWebhookDeliveries(ctx, now, now.Add(d.lease), d.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "[Service][Webhook] error while claiming deliveries")
+	}
+
+	// deliveries are claimed across tenants; each is attempted within its own.
+	var attempted int
+	subscriptions := make(map[string][]*domain.WebhookSubscription)
+	for _, delivery := range deliveries {
+		ctx := infrastructure.WithTenantID(ctx, delivery.TenantID)
+		if _, ok := subscriptions[delivery.TenantID]; !ok {
+			subs, err := d.repo.ListWebhookSubscriptions(ctx)
+			if err != nil {
+				return attempted, errors.Wrapf(err, "[Service][Webhook] error while listing subscriptions")
+			}
+			subscriptions[delivery.TenantID] = subs
+		}
+
+		sub := findSubscription(subscriptions[delivery.TenantID], delivery.SubscriptionID)
+		if sub == nil {
+			// the subscription was deleted after the delivery was claimed.
+			if err := d.repo.DeleteWebhookDelivery(ctx, delivery.DeliveryID); err != nil {
+				return attempted, errors.Wrapf(err, "[Service][Webhook] error while deleting delivery")
+			}
+			continue
+		}
+		if err := d.attempt(ctx, sub, delivery); err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, nil
+}
+
+// attempt sends the delivery once and stores the outcome.
+func (d *WebhookDeliverer) attempt(
+	ctx context.Context,
+	sub *domain.WebhookSubscription,
+	delivery *domain.WebhookDelivery,
+) error {
+	statusCode, sendErr := d.send(ctx, sub, delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	if sendErr == nil {
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.LastError = sendErr.Error()
+	}
+
+	return d.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if sendErr != nil && delivery.Attempts >= d.maxAttempts {
+			letter := &domain.WebhookDeadLetter{
+				SubscriptionID: delivery.SubscriptionID,
+				EventID:        delivery.EventID,
+				EventType:      delivery.EventType,
+				Payload:        delivery.Payload,
+				Attempts:       delivery.Attempts,
+				LastStatusCode: delivery.LastStatusCode,
+				LastError:      delivery.LastError,
+			}
+			if err := d.repo.CreateWebhookDeadLetter(ctx, letter); err != nil {
+				return errors.Wrapf(err, "[Service][Webhook] error while creating dead letter")
+			}
+			if err := d.repo.DeleteWebhookDelivery(ctx, delivery.DeliveryID); err != nil {
+				return errors.Wrapf(err, "[Service][Webhook] error while deleting delivery")
+			}
+			return nil
+		}
+
+		if sendErr != nil {
+			delivery.NextAttemptAt = now.Add(d.backoffAfter(delivery.Attempts))
+		}
+		if err := d.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			return errors.Wrapf(err, "[Service][Webhook] error while updating delivery")
+		}
+		return nil
+	})
+}
+
+// send posts the delivery to the subscription and returns the status code of the response.
+func (d *WebhookDeliverer) send(
+	ctx context.Context,
+	sub *domain.WebhookSubscription,
+	delivery *domain.WebhookDelivery,
+) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(sub.Secret, timestamp, body))
+	req.Header.Set(HeaderWebhookEventID, strconv.FormatUint(uint64(delivery.EventID), 10))
+	req.Header.Set(HeaderWebhookEventType, delivery.EventType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorLength))
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// backoffAfter returns the wait after the given number of failed attempts.
+func (d *WebhookDeliverer) backoffAfter(attempts int) time.Duration {
+	backoff := d.backoff
+	for i := 1; i < attempts && backoff < d.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.maxBackoff)
+}
+
+func findSubscription(subscriptions []*domain.WebhookSubscription, id uint) *domain.WebhookSubscription {
+	for _, sub := range subscriptions {
+		if sub.SubscriptionID == id {
+			return sub
+		}
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package application_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newWebhookReceiver returns a receiver answering status to the deliveries signed with the secret and 401 to the
+// others.
+func newWebhookReceiver(t *testing.T, secret string, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(application.HeaderWebhookTimestamp), 10, 64)
+		if r.Header.Get(application.HeaderWebhookSignature) != application.SignWebhook(secret, timestamp, body) ||
+			r.Header.Get(application.HeaderWebhookEventType) != domain.DomainEventContactCreated {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestWebhookDeliverer_Deliver ...
+func TestWebhookDeliverer_Deliver(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Secret   string
+		Status   int
+		Attempts int
+		Setup    func(tenantCtx interface{}, repo *mockObject.ContactRepositoryMock)
+	}{
+		{
+			Name:   "Signed delivery is accepted and marked delivered",
+			Secret: "s3cret",
+			Status: http.StatusNoContent,
+			Setup: func(tenantCtx interface{}, repo *mockObject.ContactRepositoryMock) {
+				repo.On("UpdateWebhookDelivery", tenantCtx, mock.MatchedBy(func(d *domain.WebhookDelivery) bool {
+					return d.Attempts == 1 && d.DeliveredAt != nil && d.LastStatusCode == http.StatusNoContent
+				})).Return(nil).Once()
+			},
+		},
+		{
+			Name:   "Delivery signed with another secret is rejected",
+			Secret: "rotated",
+			Status: http.StatusNoContent,
+			Setup: func(tenantCtx interface{}, repo *mockObject.ContactRepositoryMock) {
+				repo.On("UpdateWebhookDelivery", tenantCtx, mock.MatchedBy(func(d *domain.WebhookDelivery) bool {
+					return d.DeliveredAt == nil && d.LastStatusCode == http.StatusUnauthorized
+				})).Return(nil).Once()
+			},
+		},
+		{
+			Name:     "Failed attempt is retried after a doubled backoff",
+			Secret:   "s3cret",
+			Status:   http.StatusInternalServerError,
+			Attempts: 1,
+			Setup: func(tenantCtx interface{}, repo *mockObject.ContactRepositoryMock) {
+				repo.On("UpdateWebhookDelivery", tenantCtx, mock.MatchedBy(func(d *domain.WebhookDelivery) bool {
+					wait := time.Until(d.NextAttemptAt)
+					return d.Attempts == 2 && d.DeliveredAt == nil && d.LastError != "" &&
+						wait > 1500*time.Millisecond && wait <= 2*time.Second
+				})).Return(nil).Once()
+			},
+		},
+		{
+			Name:     "Delivery out of attempts is moved to the dead letters",
+			Secret:   "s3cret",
+			Status:   http.StatusBadGateway,
+			Attempts: 2,
+			Setup: func(tenantCtx interface{}, repo *mockObject.ContactRepositoryMock) {
+				repo.On("CreateWebhookDeadLetter", tenantCtx, mock.MatchedBy(func(l *domain.WebhookDeadLetter) bool {
+					return l.SubscriptionID == 1 && l.EventID == 9 && l.Attempts == 3 &&
+						l.LastStatusCode == http.StatusBadGateway
+				})).Return(nil).Once()
+				repo.On("DeleteWebhookDelivery", tenantCtx, uint(5)).Return(nil).Once()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+			receiver := newWebhookReceiver(t, "s3cret", tt.Status)
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("WithTransaction", ctx).Return(nil).Once()
+			repoMock.On("ClaimWebhookDeliveries", ctx, mock.Anything, mock.Anything, 10).Run(func(args mock.Arguments) {
+				// the batch is leased for as long as sending each of its deliveries may take.
+				assert.Equal(t, 100*time.Second, args.Get(2).(time.Time).Sub(args.Get(1).(time.Time)))
+			}).Return([]*domain.WebhookDelivery{{
+				DeliveryID:     5,
+				TenantID:       "shop-a",
+				SubscriptionID: 1,
+				EventID:        9,
+				EventType:      domain.DomainEventContactCreated,
+				Payload:        `{"event_id":9}`,
+				Attempts:       tt.Attempts,
+			}}, nil).Once()
+			repoMock.On("ListWebhookSubscriptions", tenantContext("shop-a")).Return([]*domain.WebhookSubscription{
+				{SubscriptionID: 1, URL: receiver.URL, Secret: tt.Secret},
+			}, nil).Once()
+			repoMock.On("WithTransaction", tenantContext("shop-a")).Return(nil).Once()
+			tt.Setup(tenantContext("shop-a"), repoMock)
+
+			deliverer := application.NewWebhookDeliverer(repoMock, receiver.Client(), config.WebhookConfig{
+				MaxAttempts:   3,
+				BackoffMillis: 1000,
+				BatchSize:     10,
+			})
+			attempted, err := deliverer.Deliver(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, attempted)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestWebhookDeliverer_InternalAddresses ...
+func TestWebhookDeliverer_InternalAddresses(t *testing.T) {
+	tests := []struct {
+		Name         string
+		AllowedHosts []string
+		Expected     func(d *domain.WebhookDelivery) bool
+	}{
+		{
+			Name: "Delivery to a loopback address is refused",
+			Expected: func(d *domain.WebhookDelivery) bool {
+				return d.DeliveredAt == nil && d.LastStatusCode == 0 && strings.Contains(d.LastError, "internal address")
+			},
+		},
+		{
+			Name:         "Delivery to an allowed host is sent",
+			AllowedHosts: []string{"127.0.0.1"},
+			Expected: func(d *domain.WebhookDelivery) bool {
+				return d.DeliveredAt != nil && d.LastStatusCode == http.StatusNoContent
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+			receiver := newWebhookReceiver(t, "s3cret", http.StatusNoContent)
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("WithTransaction", ctx).Return(nil).Once()
+			repoMock.On("ClaimWebhookDeliveries", ctx, mock.Anything, mock.Anything, 10).
+				Return([]*domain.WebhookDelivery{{
+					DeliveryID:     5,
+					TenantID:       "shop-a",
+					SubscriptionID: 1,
+					EventID:        9,
+					EventType:      domain.DomainEventContactCreated,
+					Payload:        `{"event_id":9}`,
+				}}, nil).Once()
+			repoMock.On("ListWebhookSubscriptions", tenantContext("shop-a")).Return([]*domain.WebhookSubscription{
+				{SubscriptionID: 1, URL: receiver.URL, Secret: "s3cret"},
+			}, nil).Once()
+			repoMock.On("WithTransaction", tenantContext("shop-a")).Return(nil).Once()
+			repoMock.On("UpdateWebhookDelivery", tenantContext("shop-a"), mock.MatchedBy(tt.Expected)).
+				Return(nil).Once()
+
+			deliverer := application.NewWebhookDeliverer(repoMock, nil, config.WebhookConfig{
+				MaxAttempts:  3,
+				BatchSize:    10,
+				AllowedHosts: tt.AllowedHosts,
+			})
+			attempted, err := deliverer.Deliver(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, attempted)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestWebhookPublisher_Publish ...
+func TestWebhookPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	repoMock := new(mockObject.ContactRepositoryMock)
+	repoMock.On("ListWebhookSubscriptions", ctx).Return([]*domain.WebhookSubscription{
+		{SubscriptionID: 1, URL: "https://crm.example.com/hooks"},
+		{SubscriptionID: 2, URL: "https://mail.example.com/hooks", EventTypes: domain.DomainEventClustersMerged},
+	}, nil).Once()
+	repoMock.On("CreateWebhookDeliveries", ctx, mock.MatchedBy(func(d []*domain.WebhookDelivery) bool {
+		return len(d) == 1 && d[0].SubscriptionID == 1 && d[0].EventID == 9 &&
+			d[0].Payload == `{"event_id":9,"tenant_id":"shop-a","type":"ContactCreated","contact_id":3,`+
+				`"created_at":"2023-04-01T00:00:00Z","payload":{"contact_id":3}}`
+	})).Return(nil).Once()
+
+	publisher := application.NewWebhookPublisher(repoMock)
+	err := publisher.Publish(ctx, &domain.OutboxEvent{
+		EventID:   9,
+		TenantID:  "shop-a",
+		Type:      domain.DomainEventContactCreated,
+		ContactID: 3,
+		Payload:   `{"contact_id":3}`,
+		CreatedAt: createdAt,
+	})
+
+	assert.NoError(t, err)
+	repoMock.AssertExpectations(t)
+}
+
+// TestService_CreateWebhookSubscription ...
+func TestService_CreateWebhookSubscription(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Request       application.WebhookSubscriptionRequest
+		ExpectedError error
+	}{
+		{
+			Name: "Subscription with a generated secret",
+			Request: application.WebhookSubscriptionRequest{
+				URL:        "https://crm.example.com/hooks",
+				EventTypes: []string{domain.DomainEventClustersMerged},
+			},
+		},
+		{
+			Name:          "URL that is not http",
+			Request:       application.WebhookSubscriptionRequest{URL: "ftp://crm.example.com/hooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "URL that is not https",
+			Request:       application.WebhookSubscriptionRequest{URL: "http://crm.example.com/hooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "Loopback address",
+			Request:       application.WebhookSubscriptionRequest{URL: "https://127.0.0.1:8000/admin/webhooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "Localhost",
+			Request:       application.WebhookSubscriptionRequest{URL: "https://LOCALHOST/hooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "Private address",
+			Request:       application.WebhookSubscriptionRequest{URL: "https://10.0.3.7/hooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "Link-local address",
+			Request:       application.WebhookSubscriptionRequest{URL: "https://169.254.169.254/latest/meta-data"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name:          "Unspecified IPv6 address",
+			Request:       application.WebhookSubscriptionRequest{URL: "https://[::]/hooks"},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+		{
+			Name: "Allowed host over http",
+			Request: application.WebhookSubscriptionRequest{
+				URL:        "http://crm.internal:8080/hooks",
+				EventTypes: []string{domain.DomainEventClustersMerged},
+			},
+		},
+		{
+			Name: "Unknown event type",
+			Request: application.WebhookSubscriptionRequest{
+				URL:        "https://crm.example.com/hooks",
+				EventTypes: []string{"ContactDeleted"},
+			},
+			ExpectedError: application.ErrInvalidWebhookSubscription,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			if tt.ExpectedError == nil {
+				repoMock.On("CreateWebhookSubscription", ctx, mock.Anything).Return(nil).Once()
+			}
+
+			service := application.NewService(
+				repoMock, config.IdentityConfig{}, application.WithWebhookAllowedHosts([]string{"crm.internal"}),
+			)
+			subscription, err := service.CreateWebhookSubscription(ctx, tt.Request)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, subscription.Secret, 64)
+			assert.Equal(t, domain.DomainEventClustersMerged, subscription.EventTypes)
+		})
+	}
+}
+
+// TestService_ReplayWebhookDeadLetter ...
+func TestService_ReplayWebhookDeadLetter(t *testing.T) {
+	letter := &domain.WebhookDeadLetter{
+		DeadLetterID:   4,
+		SubscriptionID: 1,
+		EventID:        9,
+		EventType:      domain.DomainEventContactCreated,
+		Payload:        `{"event_id":9}`,
+		Attempts:       8,
+	}
+
+	tests := []struct {
+		Name          string
+		Setup         func(ctx context.Context, repo *mockObject.ContactRepositoryMock)
+		ExpectedError error
+	}{
+		{
+			Name: "Dead letter is queued again",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetWebhookDeadLetter", ctx, uint(4)).Return(letter, nil).Once()
+				repo.On("ListWebhookSubscriptions", ctx).
+					Return([]*domain.WebhookSubscription{{SubscriptionID: 1}}, nil).Once()
+				repo.On("CreateWebhookDeliveries", ctx, mock.MatchedBy(func(d []*domain.WebhookDelivery) bool {
+					return len(d) == 1 && d[0].SubscriptionID == 1 && d[0].EventID == 9 && d[0].Attempts == 0
+				})).Return(nil).Once()
+				repo.On("MarkWebhookDeadLetterReplayed", ctx, uint(4), mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			Name: "Subscription deleted since",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetWebhookDeadLetter", ctx, uint(4)).Return(letter, nil).Once()
+				repo.On("ListWebhookSubscriptions", ctx).Return([]*domain.WebhookSubscription{}, nil).Once()
+			},
+			ExpectedError: application.ErrWebhookSubscriptionNotFound,
+		},
+		{
+			Name: "Unknown dead letter",
+			Setup: func(ctx context.Context, repo *mockObject.ContactRepositoryMock) {
+				repo.On("GetWebhookDeadLetter", ctx, uint(4)).Return((*domain.WebhookDeadLetter)(nil), nil).Once()
+			},
+			ExpectedError: application.ErrWebhookDeadLetterNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repoMock := new(mockObject.ContactRepositoryMock)
+			repoMock.On("WithTransaction", ctx).Return(nil).Once()
+			tt.Setup(ctx, repoMock)
+
+			service := application.NewService(repoMock, config.IdentityConfig{})
+			delivery, err := service.ReplayWebhookDeadLetter(ctx, 4)
+
+			repoMock.AssertExpectations(t)
+			if tt.ExpectedError != nil {
+				assert.ErrorIs(t, err, tt.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, uint(9), delivery.EventID)
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +13,10 @@ import (
 type config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	Server   ServerConfig   `mapstructure:"server"`
+	Identity IdentityConfig `mapstructure:"identity"`
+	Outbox   OutboxConfig   `mapstructure:"outbox"`
+	Webhook  WebhookConfig  `mapstructure:"webhook"`
+	Tenant   TenantConfig   `mapstructure:"tenant"`
 }
 
 // Values ...
@@ -25,6 +31,7 @@ func init() {
 		"$HOME/.env", // Home directory
 		".env",
 		"../../.env",
+		"../../../.env",
 		filepath.Join(
 			os.Getenv("GOPATH"),
 			"src", "github.com", "mohitsethia", "link-identity", ".env"), // Go project directory
@@ -58,4 +65,79 @@ func init() {
 	if Values.Database.Port = os.Getenv("database.port"); Values.Database.Port == "" {
 		panic("database port cannot be empty")
 	}
+	Values.Identity.MaxTransactionAttempts = getEnvInt("identity.max_transaction_attempts", 3)
+	Values.Identity.DefaultPhoneRegion = os.Getenv("identity.default_phone_region")
+	Values.Identity.EmailPlusTagDomains = getEnvList("identity.email_plus_tag_domains")
+	Values.Identity.EmailDotInsensitiveDomains = getEnvList("identity.email_dot_insensitive_domains")
+	Values.Identity.BatchChunkSize = getEnvInt("identity.batch_chunk_size", 100)
+	Values.Identity.MaxBatchSize = getEnvInt("identity.max_batch_size", 5000)
+	Values.Identity.PrimaryElection = os.Getenv("identity.primary_election")
+	Values.Identity.SharedIdentifierThreshold = getEnvInt("identity.shared_identifier_threshold", 0)
+	Values.Identity.DenylistCacheTTLMillis = getEnvInt("identity.denylist_cache_ttl_ms", 60000)
+	switch Values.Identity.LinkingMode = os.Getenv("identity.linking_mode"); Values.Identity.LinkingMode {
+	case "", LinkingModeAll, LinkingModeVerifiedOnly:
+	default:
+		log.Fatalf("identity.linking_mode must be %s or %s", LinkingModeAll, LinkingModeVerifiedOnly)
+	}
+	Values.Outbox.PollIntervalMillis = getEnvInt("outbox.poll_interval_ms", 1000)
+	Values.Outbox.BatchSize = getEnvInt("outbox.batch_size", 100)
+	Values.Webhook.MaxAttempts = getEnvInt("webhook.max_attempts", 8)
+	Values.Webhook.BackoffMillis = getEnvInt("webhook.backoff_ms", 1000)
+	Values.Webhook.MaxBackoffMillis = getEnvInt("webhook.max_backoff_ms", 3600000)
+	Values.Webhook.TimeoutMillis = getEnvInt("webhook.timeout_ms", 10000)
+	Values.Webhook.PollIntervalMillis = getEnvInt("webhook.poll_interval_ms", 1000)
+	Values.Webhook.BatchSize = getEnvInt("webhook.batch_size", 50)
+	Values.Webhook.AllowedHosts = getEnvList("webhook.allowed_hosts")
+	Values.Tenant.APIKeys = getEnvMap("tenant.api_keys")
+	Values.Tenant.AllowTenantHeader = getEnvBool("tenant.allow_tenant_header")
+}
+
+// getEnvInt returns the integer value of the environment variable key, or def when it is not set.
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s must be an integer: %v", key, err)
+	}
+	return i
+}
+
+// getEnvBool returns the boolean value of the environment variable key, or false when it is not set.
+func getEnvBool(key string) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("%s must be a boolean: %v", key, err)
+	}
+	return b
+}
+
+// getEnvList returns the comma separated values of the environment variable key.
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getEnvMap returns the comma separated key:value pairs of the environment variable key.
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getEnvList(key) {
+		k, v, ok := strings.Cut(pair, ":")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); !ok || k == "" || v == "" {
+			log.Fatalf("%s must be a comma separated list of key:value pairs", key)
+		}
+		values[k] = v
+	}
+	return values
 }
@@ -0,0 +1,40 @@
+package config
+
+// Linking modes.
+const (
+	// LinkingModeAll links contacts through every shared identifier.
+	LinkingModeAll = "all"
+	// LinkingModeVerifiedOnly only merges clusters through verified identifiers. Unverified matches attach new
+	// contacts to a cluster but never merge clusters.
+	LinkingModeVerifiedOnly = "verified_only"
+)
+
+// IdentityConfig ...
+type IdentityConfig struct {
+	// MaxTransactionAttempts is how many times a linking transaction is run before a serialization failure is
+	// returned to the caller. Calls sharing an identifier wait for each other's locks and do not use up attempts;
+	// the attempts are spent on calls meeting only further along the graph, and on merges, splits and cluster
+	// erasures, which lock nothing. Raise it when such calls hit the same clusters concurrently.
+	MaxTransactionAttempts int `mapstructure:"max_transaction_attempts"`
+	// DefaultPhoneRegion is the ISO 3166-1 alpha-2 region of phone numbers sent without a country code.
+	DefaultPhoneRegion string `mapstructure:"default_phone_region"`
+	// EmailPlusTagDomains are the email domains whose addresses ignore a "+tag" in the local part.
+	EmailPlusTagDomains []string `mapstructure:"email_plus_tag_domains"`
+	// EmailDotInsensitiveDomains are the email domains whose addresses ignore dots in the local part.
+	EmailDotInsensitiveDomains []string `mapstructure:"email_dot_insensitive_domains"`
+	// BatchChunkSize is how many items of a batch identify share one transaction.
+	BatchChunkSize int `mapstructure:"batch_chunk_size"`
+	// MaxBatchSize is the largest number of items a batch identify accepts.
+	MaxBatchSize int `mapstructure:"max_batch_size"`
+	// PrimaryElection names the policy electing the primary of a cluster: "oldest" (the default), "loyalty_id" or
+	// "recently_verified".
+	PrimaryElection string `mapstructure:"primary_election"`
+	// SharedIdentifierThreshold is the number of contacts an identifier may be carried by before it is flagged and
+	// no longer links contacts. 0 disables the guard.
+	SharedIdentifierThreshold int `mapstructure:"shared_identifier_threshold"`
+	// DenylistCacheTTLMillis is how long the denylist of a tenant is cached before it is loaded again. Changes made
+	// through this instance take effect at once; it bounds how long those made through another one go unseen.
+	DenylistCacheTTLMillis int `mapstructure:"denylist_cache_ttl_ms"`
+	// LinkingMode is LinkingModeAll (the default) or LinkingModeVerifiedOnly.
+	LinkingMode string `mapstructure:"linking_mode"`
+}
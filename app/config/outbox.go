@@ -0,0 +1,9 @@
+package config
+
+// OutboxConfig ...
+type OutboxConfig struct {
+	// PollIntervalMillis is how long the dispatcher waits for new events once the outbox is drained.
+	PollIntervalMillis int `mapstructure:"poll_interval_ms"`
+	// BatchSize is how many events the dispatcher publishes per transaction.
+	BatchSize int `mapstructure:"batch_size"`
+}
@@ -0,0 +1,11 @@
+package config
+
+// TenantConfig ...
+type TenantConfig struct {
+	// APIKeys maps the API keys requests authenticate with to their tenant.
+	APIKeys map[string]string `mapstructure:"api_keys"`
+	// AllowTenantHeader lets requests name their tenant in the X-Tenant-ID header when no API keys are configured.
+	// Any client can then act on any tenant, so it is meant for local development only. Without it and without API
+	// keys, every request belongs to the default tenant and requests naming another one are rejected.
+	AllowTenantHeader bool `mapstructure:"allow_tenant_header"`
+}
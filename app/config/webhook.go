@@ -0,0 +1,21 @@
+package config
+
+// WebhookConfig ...
+type WebhookConfig struct {
+	// MaxAttempts is how many times a delivery is attempted before it is moved to the dead letters.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// BackoffMillis is the wait after the first failed attempt. It doubles after every further failure, up to
+	// MaxBackoffMillis.
+	BackoffMillis    int `mapstructure:"backoff_ms"`
+	MaxBackoffMillis int `mapstructure:"max_backoff_ms"`
+	// TimeoutMillis bounds every attempt.
+	TimeoutMillis int `mapstructure:"timeout_ms"`
+	// PollIntervalMillis is how long the deliverer waits for due deliveries once none are left.
+	PollIntervalMillis int `mapstructure:"poll_interval_ms"`
+	// BatchSize is how many deliveries are claimed at once. A claimed batch is leased to the deliverer for BatchSize
+	// times TimeoutMillis.
+	BatchSize int `mapstructure:"batch_size"`
+	// AllowedHosts are the hosts webhooks may be sent to over http and at loopback, private or link-local
+	// addresses. Every other webhook needs https and a public address.
+	AllowedHosts []string `mapstructure:"allowed_hosts"`
+}
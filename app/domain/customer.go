@@ -5,17 +5,30 @@ import (
 )
 
 // Contact ...
+// A contact is one sighting of a customer and carries the identifiers it was seen with. Contacts sharing an
+// identifier form a cluster: the primary contact and the secondary contacts linked to it. Every contact belongs
+// to a tenant, and contacts are only ever linked to contacts of their own tenant.
 type Contact struct {
 	Model
-	ContactID        uint           `json:"contact_id,omitempty" gorm:"primaryKey; unique; not null; autoIncrement"`
-	Email            sql.NullString `db:"email" gorm:"column:email"`
-	Phone            sql.NullString `db:"phone" gorm:"column:phone"`
-	LinkedID         uint           `json:"linked_id,omitempty"`
-	LinkedPrecedence string         `json:"linked_precedence,omitempty" gorm:"not null" default:"primary"`
-	Deleted          sql.NullBool   `db:"deleted" gorm:"column:deleted"`
+	ContactID        uint          `json:"contact_id,omitempty" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID         string        `json:"-" gorm:"not null; default:default; index"`
+	Identifiers      []*Identifier `json:"identifiers,omitempty" gorm:"foreignKey:ContactID"`
+	LinkedID         uint          `json:"linked_id,omitempty"`
+	LinkedPrecedence string        `json:"linked_precedence,omitempty" gorm:"not null" default:"primary"`
+	Deleted          sql.NullBool  `db:"deleted" gorm:"column:deleted"`
 }
 
 // TableName ...
 func (c *Contact) TableName() string {
 	return "contact"
 }
+
+// HasIdentifier reports whether the contact carries the identifier.
+func (c *Contact) HasIdentifier(key IdentifierKey) bool {
+	for _, i := range c.Identifiers {
+		if i.Key() == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// Ways a denylist entry matches identifiers.
+const (
+	// DenylistMatchExact matches the identifier whose normalized value equals the value of the entry.
+	DenylistMatchExact = "exact"
+	// DenylistMatchRegex matches the identifiers whose normalized value matches the regular expression of the entry.
+	DenylistMatchRegex = "regex"
+)
+
+// DenylistEntry names identifiers that must never link contacts, such as test cards, store phone numbers or
+// placeholder emails. Denylisted identifiers are still stored on the contacts carrying them.
+type DenylistEntry struct {
+	EntryID   uint      `json:"entry_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID  string    `json:"-" gorm:"not null; default:default; index"`
+	Type      string    `json:"type" gorm:"not null; index"`
+	Match     string    `json:"match" gorm:"not null"`
+	Value     string    `json:"value" gorm:"not null"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName ...
+func (e *DenylistEntry) TableName() string {
+	return "identifier_denylist"
+}
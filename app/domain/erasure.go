@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Erasure modes.
+const (
+	// ErasureModeSoft marks rows as deleted. They are no longer read or matched but can still be restored.
+	ErasureModeSoft = "soft"
+	// ErasureModeHard deletes rows and scrubs the erased identifiers from the link events.
+	ErasureModeHard = "hard"
+)
+
+// ErasureScopeCluster is the scope of an erasure of a whole cluster. Erasures of an identifier are scoped by the
+// identifier type.
+const ErasureScopeCluster = "cluster"
+
+// ErasureReceipt records an erasure for compliance. It holds no personal data: the erased identifier is only kept
+// as a SHA-256 hash of its normalized form.
+type ErasureReceipt struct {
+	ReceiptID   uint   `json:"receipt_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID    string `json:"-" gorm:"not null; default:default; index"`
+	Mode        string `json:"mode" gorm:"not null"`
+	Scope       string `json:"scope" gorm:"not null"`
+	SubjectHash string `json:"subject_hash,omitempty"`
+	ContactID   uint   `json:"contact_id,omitempty"`
+	// DeletedContactIDs and ReelectedContactIDs are comma separated lists of contact ids.
+	DeletedContactIDs   string    `json:"deleted_contact_ids"`
+	DeletedIdentifiers  int       `json:"deleted_identifiers"`
+	ReelectedContactIDs string    `json:"reelected_contact_ids,omitempty"`
+	RequestedBy         string    `json:"requested_by" gorm:"not null"`
+	RequestID           string    `json:"request_id,omitempty"`
+	CreatedAt           time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (r *ErasureReceipt) TableName() string {
+	return "erasure_receipt"
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// FlaggedIdentifier is an identifier carried by so many contacts, such as the phone of a call centre, that it is
+// no longer followed when clusters are linked. ContactCount is the number of contacts carrying it when it was
+// flagged.
+type FlaggedIdentifier struct {
+	FlaggedIdentifierID uint      `json:"flagged_identifier_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID            string    `json:"-" gorm:"not null; default:default; uniqueIndex:idx_flagged_identifier_tenant_key"`
+	Type                string    `json:"type" gorm:"not null; uniqueIndex:idx_flagged_identifier_tenant_key"`
+	Value               string    `json:"value" gorm:"not null; uniqueIndex:idx_flagged_identifier_tenant_key"`
+	ContactCount        int       `json:"contact_count" gorm:"not null"`
+	CreatedAt           time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (f *FlaggedIdentifier) TableName() string {
+	return "flagged_identifier"
+}
+
+// Key ...
+func (f *FlaggedIdentifier) Key() IdentifierKey {
+	return IdentifierKey{Type: f.Type, Value: f.Value}
+}
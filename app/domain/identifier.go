@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// Built-in identifier types. Other types can be registered with the application's identifier registry.
+const (
+	IdentifierTypeEmail               = "email"
+	IdentifierTypePhone               = "phone"
+	IdentifierTypeDeviceID            = "device_id"
+	IdentifierTypeLoyaltyID           = "loyalty_id"
+	IdentifierTypeShippingAddressHash = "shipping_address_hash"
+)
+
+// Identifier is a typed value, such as an email or a phone, that links every contact carrying it.
+// Value is the normalized form the identifier is matched by, RawValue the value as it was received.
+// VerifiedAt is set once the customer of the contact proved to own the identifier, for instance with an OTP.
+type Identifier struct {
+	Model
+	IdentifierID uint       `json:"identifier_id,omitempty" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID     string     `json:"-" gorm:"not null; default:default; index:idx_contact_identifier_tenant_type_value"`
+	ContactID    uint       `json:"contact_id,omitempty" gorm:"not null; index"`
+	Type         string     `json:"type" gorm:"not null; index:idx_contact_identifier_tenant_type_value"`
+	Value        string     `json:"value" gorm:"not null; index:idx_contact_identifier_tenant_type_value"`
+	RawValue     string     `json:"raw_value" gorm:"not null"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+}
+
+// TableName ...
+func (i *Identifier) TableName() string {
+	return "contact_identifier"
+}
+
+// DisplayValue returns the value as it was received, or the normalized one for identifiers stored without it.
+func (i *Identifier) DisplayValue() string {
+	if i.RawValue == "" {
+		return i.Value
+	}
+	return i.RawValue
+}
+
+// Key ...
+func (i *Identifier) Key() IdentifierKey {
+	return IdentifierKey{Type: i.Type, Value: i.Value}
+}
+
+// IdentifierKey identifies the normalized value of an identifier across contacts.
+type IdentifierKey struct {
+	Type  string
+	Value string
+}
+
+// String ...
+func (k IdentifierKey) String() string {
+	return k.Type + ":" + k.Value
+}
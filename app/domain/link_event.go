@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Actions of a LinkEvent.
+const (
+	// LinkActionCreate records a contact being inserted, as a primary or linked to one.
+	LinkActionCreate = "create"
+	// LinkActionLink records a secondary being re-pointed to another primary.
+	LinkActionLink = "link"
+	// LinkActionDemote records a primary becoming the secondary of an older primary.
+	LinkActionDemote = "demote"
+	// LinkActionMerge records a contact relinked by a merge requested by hand.
+	LinkActionMerge = "merge"
+	// LinkActionSplit records a contact relinked by a split.
+	LinkActionSplit = "split"
+	// LinkActionErase records a contact relinked because contacts of its cluster were erased.
+	LinkActionErase = "erase"
+)
+
+// LinkEvent is an entry of the append-only log of link changes. It keeps the link of the contact before and after
+// the change and, when there is one, the identifier that caused it and the observation it came with.
+type LinkEvent struct {
+	EventID          uint      `json:"event_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID         string    `json:"-" gorm:"not null; default:default; index"`
+	ContactID        uint      `json:"contact_id" gorm:"not null; index"`
+	Action           string    `json:"action" gorm:"not null"`
+	BeforeLinkedID   uint      `json:"before_linked_id"`
+	AfterLinkedID    uint      `json:"after_linked_id"`
+	BeforePrecedence string    `json:"before_precedence"`
+	AfterPrecedence  string    `json:"after_precedence"`
+	IdentifierType   string    `json:"identifier_type,omitempty"`
+	IdentifierValue  string    `json:"identifier_value,omitempty"`
+	RequestID        string    `json:"request_id,omitempty"`
+	ObservationID    uint      `json:"observation_id,omitempty" gorm:"index"`
+	CreatedAt        time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (e *LinkEvent) TableName() string {
+	return "contact_link_event"
+}
@@ -0,0 +1,18 @@
+package domain
+
+// ContactMerge records a merge of two clusters requested by hand, such as by a support agent.
+// PrimaryContactID is the primary the clusters were merged under, MergedContactID the primary it demoted.
+type ContactMerge struct {
+	Model
+	MergeID          uint   `json:"merge_id,omitempty" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID         string `json:"-" gorm:"not null; default:default; index"`
+	PrimaryContactID uint   `json:"primary_contact_id" gorm:"not null; index"`
+	MergedContactID  uint   `json:"merged_contact_id" gorm:"not null; index"`
+	RequestedBy      string `json:"requested_by" gorm:"not null"`
+	Reason           string `json:"reason"`
+}
+
+// TableName ...
+func (m *ContactMerge) TableName() string {
+	return "contact_merge"
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Observation records where the identifiers of an identify call were seen, such as the order they came with. It
+// belongs to the contact the call created or, when it created none, to the primary of the cluster it matched. The
+// link events the call caused refer to it, so it tells why contacts were linked.
+type Observation struct {
+	ObservationID   uint            `json:"observation_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID        string          `json:"-" gorm:"not null; default:default; index"`
+	ContactID       uint            `json:"contact_id" gorm:"not null; index"`
+	Source          string          `json:"source,omitempty"`
+	ExternalOrderID string          `json:"external_order_id,omitempty" gorm:"index"`
+	OccurredAt      time.Time       `json:"occurred_at" gorm:"not null"`
+	Metadata        json.RawMessage `json:"metadata,omitempty" gorm:"type:jsonb"`
+	RequestID       string          `json:"request_id,omitempty"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (o *Observation) TableName() string {
+	return "contact_observation"
+}
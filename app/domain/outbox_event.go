@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Types of the domain events written to the outbox.
+const (
+	// DomainEventContactCreated is written for every contact inserted, as a primary or linked to one.
+	DomainEventContactCreated = "ContactCreated"
+	// DomainEventContactLinked is written for every contact re-pointed to another primary, or promoted to primary.
+	DomainEventContactLinked = "ContactLinked"
+	// DomainEventPrimaryDemoted is written for every primary becoming the secondary of another primary.
+	DomainEventPrimaryDemoted = "PrimaryDemoted"
+	// DomainEventClustersMerged is written once per primary that other clusters were merged into.
+	DomainEventClustersMerged = "ClustersMerged"
+	// DomainEventContactErased is written for every contact deleted by an erasure, with the primary it had.
+	DomainEventContactErased = "ContactErased"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the contact changes it describes, and
+// published afterwards. PublishedAt is set once it has been published. ContactID is the contact the event is
+// about, the primary for DomainEventClustersMerged. Payload is the JSON of ContactEventPayload or
+// ClustersMergedPayload.
+type OutboxEvent struct {
+	EventID     uint       `json:"event_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID    string     `json:"-" gorm:"not null; default:default; index"`
+	Type        string     `json:"type" gorm:"not null"`
+	ContactID   uint       `json:"contact_id" gorm:"not null"`
+	Payload     string     `json:"payload" gorm:"type:jsonb; not null"`
+	RequestID   string     `json:"request_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	PublishedAt *time.Time `json:"published_at,omitempty" gorm:"index"`
+}
+
+// TableName ...
+func (e *OutboxEvent) TableName() string {
+	return "outbox_event"
+}
+
+// ContactEventPayload is the payload of DomainEventContactCreated, DomainEventContactLinked,
+// DomainEventPrimaryDemoted and DomainEventContactErased. It holds no identifier values, so that erased customers
+// do not live on in the outbox.
+type ContactEventPayload struct {
+	ContactID                uint   `json:"contact_id"`
+	PrimaryContactID         uint   `json:"primary_contact_id"`
+	PreviousPrimaryContactID uint   `json:"previous_primary_contact_id,omitempty"`
+	Precedence               string `json:"precedence"`
+	// Action is the action of the link event, such as LinkActionMerge or LinkActionSplit.
+	Action         string `json:"action"`
+	IdentifierType string `json:"identifier_type,omitempty"`
+	ObservationID  uint   `json:"observation_id,omitempty"`
+}
+
+// ClustersMergedPayload is the payload of DomainEventClustersMerged.
+type ClustersMergedPayload struct {
+	PrimaryContactID        uint   `json:"primary_contact_id"`
+	MergedPrimaryContactIDs []uint `json:"merged_primary_contact_ids"`
+}
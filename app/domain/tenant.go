@@ -0,0 +1,5 @@
+package domain
+
+// DefaultTenantID is the tenant of requests that do not name one, and of the rows written before there were
+// tenants.
+const DefaultTenantID = "default"
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookSubscription is a URL the domain events are delivered to. EventTypes is a comma separated list of the
+// event types it receives; an empty list receives every type. The Secret signs the deliveries and is only shown
+// when the subscription is created.
+type WebhookSubscription struct {
+	SubscriptionID uint      `json:"subscription_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID       string    `json:"-" gorm:"not null; default:default; index"`
+	URL            string    `json:"url" gorm:"not null"`
+	Secret         string    `json:"-" gorm:"not null"`
+	EventTypes     string    `json:"event_types,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (s *WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
+
+// Receives reports whether the subscription receives events of the type.
+func (s *WebhookSubscription) Receives(eventType string) bool {
+	if s.EventTypes == "" {
+		return true
+	}
+	for _, t := range strings.Split(s.EventTypes, ",") {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is a domain event waiting to be delivered to a subscription, or delivered at DeliveredAt.
+// Payload is the body it is sent with. A failed attempt is tried again at NextAttemptAt.
+type WebhookDelivery struct {
+	DeliveryID     uint       `json:"delivery_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID       string     `json:"-" gorm:"not null; default:default; index"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null; index"`
+	EventID        uint       `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:jsonb; not null"`
+	Attempts       int        `json:"attempts" gorm:"not null"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"not null; index"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+// WebhookDeadLetter is a delivery that failed every attempt. It can be replayed as a new delivery.
+type WebhookDeadLetter struct {
+	DeadLetterID   uint       `json:"dead_letter_id" gorm:"primaryKey; unique; not null; autoIncrement"`
+	TenantID       string     `json:"-" gorm:"not null; default:default; index"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null; index"`
+	EventID        uint       `json:"event_id" gorm:"not null"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:jsonb; not null"`
+	Attempts       int        `json:"attempts" gorm:"not null"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	ReplayedAt     *time.Time `json:"replayed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`
+}
+
+// TableName ...
+func (d *WebhookDeadLetter) TableName() string {
+	return "webhook_dead_letter"
+}
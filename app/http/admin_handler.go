@@ -0,0 +1,355 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/utils"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+)
+
+// DenylistEntryDTO ...
+type DenylistEntryDTO struct {
+	Type string `json:"type"`
+	// Value is an identifier value for the exact match, or a regular expression for the regex match.
+	Value  string `json:"value"`
+	Match  string `json:"match"`
+	Reason string `json:"reason"`
+}
+
+// WebhookSubscriptionDTO ...
+type WebhookSubscriptionDTO struct {
+	URL string `json:"url"`
+	// Secret signs the deliveries. A random one is generated when it is empty.
+	Secret string `json:"secret,omitempty"`
+	// EventTypes are the domain event types to deliver, every type when it is empty.
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+// CreatedWebhookSubscriptionDTO is a subscription just created, the only time its secret is shown.
+type CreatedWebhookSubscriptionDTO struct {
+	*domain.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// AdminHandler serves the endpoints operators use to look after the identity graph.
+type AdminHandler struct {
+	service application.LinkIdentityService
+}
+
+// NewAdminHandler ...
+func NewAdminHandler(service application.LinkIdentityService) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+	}
+}
+
+// FlaggedIdentifiers lists the identifiers that are no longer followed because too many contacts carry them. It is
+// paged by ?after_id= and ?limit=.
+func (h *AdminHandler) FlaggedIdentifiers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	afterID, limit, err := pageParams(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	flagged, err := h.service.FlaggedIdentifiers(ctx, afterID, limit)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if flagged == nil {
+		flagged = []*domain.FlaggedIdentifier{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, flagged)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// DenylistEntries ...
+func (h *AdminHandler) DenylistEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	entries, err := h.service.DenylistEntries(ctx)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if entries == nil {
+		entries = []*domain.DenylistEntry{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, entries)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// CreateDenylistEntry ...
+func (h *AdminHandler) CreateDenylistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	model, v := decodeDenylistEntry(r)
+	if v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	entry, err := h.service.CreateDenylistEntry(ctx, model.request())
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusCreated, entry)
+	utils.ResponseJSON(w, http.StatusCreated, resp)
+}
+
+// UpdateDenylistEntry ...
+func (h *AdminHandler) UpdateDenylistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	model, v := decodeDenylistEntry(r)
+	if v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	entry, err := h.service.UpdateDenylistEntry(ctx, id, model.request())
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, entry)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// DeleteDenylistEntry ...
+func (h *AdminHandler) DeleteDenylistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if err := h.service.DeleteDenylistEntry(ctx, id); err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookSubscriptions ...
+func (h *AdminHandler) WebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscriptions, err := h.service.WebhookSubscriptions(ctx)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if subscriptions == nil {
+		subscriptions = []*domain.WebhookSubscription{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, subscriptions)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// CreateWebhookSubscription ...
+func (h *AdminHandler) CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	model := new(WebhookSubscriptionDTO)
+	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	if v := model.Validate(); v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	subscription, err := h.service.CreateWebhookSubscription(ctx, application.WebhookSubscriptionRequest{
+		URL:        model.URL,
+		Secret:     model.Secret,
+		EventTypes: model.EventTypes,
+	})
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusCreated, &CreatedWebhookSubscriptionDTO{
+		WebhookSubscription: subscription,
+		Secret:              subscription.Secret,
+	})
+	utils.ResponseJSON(w, http.StatusCreated, resp)
+}
+
+// DeleteWebhookSubscription ...
+func (h *AdminHandler) DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if err := h.service.DeleteWebhookSubscription(ctx, id); err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebhookDeadLetters lists the deliveries that failed every attempt. It is paged by ?after_id= and ?limit=.
+func (h *AdminHandler) WebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	afterID, limit, err := pageParams(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	letters, err := h.service.WebhookDeadLetters(ctx, afterID, limit)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if letters == nil {
+		letters = []*domain.WebhookDeadLetter{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, letters)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// ReplayWebhookDeadLetter queues a dead letter for delivery again and responds with the new delivery.
+func (h *AdminHandler) ReplayWebhookDeadLetter(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := idParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	delivery, err := h.service.ReplayWebhookDeadLetter(ctx, id)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusAccepted, delivery)
+	utils.ResponseJSON(w, http.StatusAccepted, resp)
+}
+
+// Validate ...
+func (v *WebhookSubscriptionDTO) Validate() *utils.ErrorResponse {
+	if strings.TrimSpace(v.URL) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "url cannot be empty")
+	}
+	return nil
+}
+
+// Validate ...
+func (v *DenylistEntryDTO) Validate() *utils.ErrorResponse {
+	if strings.TrimSpace(v.Type) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "type cannot be empty")
+	}
+	if strings.TrimSpace(v.Value) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "value cannot be empty")
+	}
+	return nil
+}
+
+func (v *DenylistEntryDTO) request() application.DenylistRequest {
+	return application.DenylistRequest{Type: v.Type, Value: v.Value, Match: v.Match, Reason: v.Reason}
+}
+
+func decodeDenylistEntry(r *http.Request) (*DenylistEntryDTO, *utils.ErrorResponse) {
+	model := new(DenylistEntryDTO)
+	if err := json.NewDecoder(r.Body).Decode(&model); err != nil {
+		return nil, utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+	}
+	if v := model.Validate(); v != nil {
+		return nil, v
+	}
+	return model, nil
+}
+
+func idParam(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 0)
+	if err != nil || id == 0 {
+		return 0, errors.New("id must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+// pageParams parses the optional ?after_id= and ?limit= parameters of a paged list.
+func pageParams(r *http.Request) (uint, int, error) {
+	var afterID uint
+	if v := r.URL.Query().Get("after_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil {
+			return 0, 0, errors.New("after_id must be a non-negative integer")
+		}
+		afterID = uint(id)
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = l
+	}
+	return afterID, limit, nil
+}
@@ -0,0 +1,325 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/link-identity/app/application"
+	"github.com/link-identity/app/domain"
+	httpHandler "github.com/link-identity/app/http"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestAdminHandler_FlaggedIdentifiers ...
+func TestAdminHandler_FlaggedIdentifiers(t *testing.T) {
+	tests := []struct {
+		Name               string
+		Query              string
+		ExpectedAfterID    uint
+		ExpectedLimit      int
+		ExpectedStatusCode int
+		ExpectedResponse   string
+		IsCalled           bool
+	}{
+		{
+			Name:               "First page",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": [{
+					"flagged_identifier_id": 3,
+					"type": "phone",
+					"value": "+4930123456789",
+					"contact_count": 412,
+					"created_at": "0001-01-01T00:00:00Z"
+				}]
+			}`,
+			IsCalled: true,
+		},
+		{
+			Name:               "Next page",
+			Query:              "?after_id=3&limit=50",
+			ExpectedAfterID:    3,
+			ExpectedLimit:      50,
+			ExpectedStatusCode: http.StatusOK,
+			IsCalled:           true,
+		},
+		{
+			Name:               "Invalid limit",
+			Query:              "?limit=-1",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.IsCalled {
+				serviceMock.On("FlaggedIdentifiers", ctx, tt.ExpectedAfterID, tt.ExpectedLimit).
+					Return([]*domain.FlaggedIdentifier{{
+						FlaggedIdentifierID: 3, Type: domain.IdentifierTypePhone, Value: "+4930123456789", ContactCount: 412,
+					}}, nil).Once()
+			}
+
+			handler := httpHandler.NewAdminHandler(serviceMock)
+
+			req, err := http.NewRequest("GET", "/admin/flagged-identifiers"+tt.Query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handler.FlaggedIdentifiers).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			if tt.ExpectedResponse != "" {
+				assert.JSONEq(t, tt.ExpectedResponse, rr.Body.String())
+			}
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestAdminHandler_Denylist ...
+func TestAdminHandler_Denylist(t *testing.T) {
+	entry := &domain.DenylistEntry{EntryID: 1, Type: "email", Match: "exact", Value: "a@a.com"}
+	tests := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Body               string
+		Setup              func(service *mockObject.LinkIdentityServiceMock)
+		ExpectedStatusCode int
+	}{
+		{
+			Name:   "List",
+			Method: "GET",
+			Path:   "/admin/denylist",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("DenylistEntries", mock.Anything).Return([]*domain.DenylistEntry{entry}, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:   "Create",
+			Method: "POST",
+			Path:   "/admin/denylist",
+			Body:   `{"type": "email", "value": "a@a.com", "reason": "placeholder"}`,
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("CreateDenylistEntry", mock.Anything, application.DenylistRequest{
+					Type: "email", Value: "a@a.com", Reason: "placeholder",
+				}).Return(entry, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusCreated,
+		},
+		{
+			Name:   "Invalid pattern",
+			Method: "POST",
+			Path:   "/admin/denylist",
+			Body:   `{"type": "email", "value": "(", "match": "regex"}`,
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("CreateDenylistEntry", mock.Anything, mock.Anything).
+					Return((*domain.DenylistEntry)(nil), application.ErrInvalidDenylistEntry).Once()
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "Missing value",
+			Method:             "POST",
+			Path:               "/admin/denylist",
+			Body:               `{"type": "email"}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:   "Update unknown entry",
+			Method: "PUT",
+			Path:   "/admin/denylist/9",
+			Body:   `{"type": "email", "value": "a@a.com"}`,
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("UpdateDenylistEntry", mock.Anything, uint(9), mock.Anything).
+					Return((*domain.DenylistEntry)(nil), application.ErrDenylistEntryNotFound).Once()
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:   "Delete",
+			Method: "DELETE",
+			Path:   "/admin/denylist/1",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("DeleteDenylistEntry", mock.Anything, uint(1)).Return(nil).Once()
+			},
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "Invalid id",
+			Method:             "DELETE",
+			Path:               "/admin/denylist/a",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Setup != nil {
+				tt.Setup(serviceMock)
+			}
+
+			handler := httpHandler.NewAdminHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/admin/denylist", handler.DenylistEntries)
+			router.Post("/admin/denylist", handler.CreateDenylistEntry)
+			router.Put("/admin/denylist/{id}", handler.UpdateDenylistEntry)
+			router.Delete("/admin/denylist/{id}", handler.DeleteDenylistEntry)
+
+			req, err := http.NewRequest(tt.Method, tt.Path, bytes.NewBufferString(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestAdminHandler_Webhooks ...
+func TestAdminHandler_Webhooks(t *testing.T) {
+	subscription := &domain.WebhookSubscription{SubscriptionID: 1, URL: "https://crm.example.com/hooks", Secret: "s3cret"}
+	tests := []struct {
+		Name               string
+		Method             string
+		Path               string
+		Body               string
+		Setup              func(service *mockObject.LinkIdentityServiceMock)
+		ExpectedStatusCode int
+		ExpectedBody       string
+	}{
+		{
+			Name:   "Create shows the secret",
+			Method: "POST",
+			Path:   "/admin/webhooks",
+			Body:   `{"url": "https://crm.example.com/hooks", "event_types": ["ClustersMerged"]}`,
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("CreateWebhookSubscription", mock.Anything, application.WebhookSubscriptionRequest{
+					URL: "https://crm.example.com/hooks", EventTypes: []string{"ClustersMerged"},
+				}).Return(subscription, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusCreated,
+			ExpectedBody:       `"secret":"s3cret"`,
+		},
+		{
+			Name:               "Missing url",
+			Method:             "POST",
+			Path:               "/admin/webhooks",
+			Body:               `{}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:   "Invalid subscription",
+			Method: "POST",
+			Path:   "/admin/webhooks",
+			Body:   `{"url": "ftp://crm.example.com"}`,
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("CreateWebhookSubscription", mock.Anything, mock.Anything).
+					Return((*domain.WebhookSubscription)(nil), application.ErrInvalidWebhookSubscription).Once()
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:   "List hides the secrets",
+			Method: "GET",
+			Path:   "/admin/webhooks",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("WebhookSubscriptions", mock.Anything).
+					Return([]*domain.WebhookSubscription{subscription}, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedBody:       `"url":"https://crm.example.com/hooks"`,
+		},
+		{
+			Name:   "Delete unknown subscription",
+			Method: "DELETE",
+			Path:   "/admin/webhooks/9",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("DeleteWebhookSubscription", mock.Anything, uint(9)).
+					Return(application.ErrWebhookSubscriptionNotFound).Once()
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:   "Dead letters",
+			Method: "GET",
+			Path:   "/admin/webhooks/dead-letters?after_id=3",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("WebhookDeadLetters", mock.Anything, uint(3), 0).
+					Return([]*domain.WebhookDeadLetter{{DeadLetterID: 4}}, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:   "Replay",
+			Method: "POST",
+			Path:   "/admin/webhooks/dead-letters/4/replay",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("ReplayWebhookDeadLetter", mock.Anything, uint(4)).
+					Return(&domain.WebhookDelivery{DeliveryID: 12}, nil).Once()
+			},
+			ExpectedStatusCode: http.StatusAccepted,
+		},
+		{
+			Name:   "Replay unknown dead letter",
+			Method: "POST",
+			Path:   "/admin/webhooks/dead-letters/9/replay",
+			Setup: func(service *mockObject.LinkIdentityServiceMock) {
+				service.On("ReplayWebhookDeadLetter", mock.Anything, uint(9)).
+					Return((*domain.WebhookDelivery)(nil), application.ErrWebhookDeadLetterNotFound).Once()
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Setup != nil {
+				tt.Setup(serviceMock)
+			}
+
+			handler := httpHandler.NewAdminHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/admin/webhooks", handler.WebhookSubscriptions)
+			router.Post("/admin/webhooks", handler.CreateWebhookSubscription)
+			router.Delete("/admin/webhooks/{id}", handler.DeleteWebhookSubscription)
+			router.Get("/admin/webhooks/dead-letters", handler.WebhookDeadLetters)
+			router.Post("/admin/webhooks/dead-letters/{id}/replay", handler.ReplayWebhookDeadLetter)
+
+			req, err := http.NewRequest(tt.Method, tt.Path, bytes.NewBufferString(tt.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.ExpectedBody)
+			if tt.Method == "GET" {
+				assert.NotContains(t, rr.Body.String(), "s3cret")
+			}
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
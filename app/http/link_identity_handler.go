@@ -3,15 +3,25 @@ package http
 import (
 	"encoding/json"
 	"net/http"
-	"net/mail"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/link-identity/app/application"
 	"github.com/link-identity/app/domain"
 	"github.com/link-identity/app/utils"
 
-	"github.com/nyaruka/phonenumbers"
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
 )
 
+const exportFlushInterval = 100
+
+var exportContentTypes = map[string]string{
+	application.ExportFormatNDJSON: "application/x-ndjson",
+	application.ExportFormatCSV:    "text/csv; charset=utf-8",
+}
+
 type (
 	// LinkIdentityHandler ...
 	LinkIdentityHandler struct {
@@ -20,18 +30,111 @@ type (
 
 	// RequestDTO ...
 	RequestDTO struct {
-		Email string `json:"email"`
-		Phone string `json:"phone"`
+		Email *string `json:"email"`
+		Phone *string `json:"phone"`
+		// EmailVerified and PhoneVerified report that the customer proved to own the email or the phone.
+		EmailVerified bool `json:"email_verified,omitempty"`
+		PhoneVerified bool `json:"phone_verified,omitempty"`
+		// Identifiers holds identifiers of any registered type, such as device_id or loyalty_id.
+		Identifiers []IdentifierDTO `json:"identifiers,omitempty"`
+		// Source, ExternalOrderID, OccurredAt and Metadata describe where the identifiers were seen. They are
+		// recorded as an observation of the contact, which the link events of the request refer to.
+		Source          string          `json:"source,omitempty"`
+		ExternalOrderID string          `json:"external_order_id,omitempty"`
+		OccurredAt      *time.Time      `json:"occurred_at,omitempty"`
+		Metadata        json.RawMessage `json:"metadata,omitempty"`
+		// DryRun plans the linking without writing anything.
+		DryRun bool `json:"dry_run,omitempty"`
+	}
+
+	// IdentifierDTO ...
+	IdentifierDTO struct {
+		Type     string `json:"type"`
+		Value    string `json:"value"`
+		Verified bool   `json:"verified,omitempty"`
+	}
+
+	// VerifyRequestDTO names an identifier of a contact the customer proved to own.
+	VerifyRequestDTO struct {
+		ContactID uint   `json:"contact_id"`
+		Type      string `json:"type"`
+		Value     string `json:"value"`
 	}
 
 	// ResponseDTO ...
 	ResponseDTO struct {
-		Contact struct {
-			PrimaryContactID    uint     `json:"PrimaryContactID"`
-			Emails              []string `json:"emails"`
-			PhoneNumbers        []string `json:"phoneNumbers"`
-			SecondaryContactIds []uint   `json:"secondaryContactIds"`
-		} `json:"contact"`
+		Contact ContactDTO `json:"contact"`
+		Created bool       `json:"created"`
+		// DryRun, Operations and NewPrimary are only set for dry runs. Contact then is the cluster as it would be.
+		// NewPrimary reports that the contact to be created would become the primary, with 0 as its placeholder ID.
+		DryRun     bool           `json:"dry_run,omitempty"`
+		Operations []OperationDTO `json:"operations,omitempty"`
+		NewPrimary bool           `json:"new_primary,omitempty"`
+	}
+
+	// BatchItemDTO is the outcome of one item of a batch identify. Contact is set when the item succeeded, Error
+	// when it failed.
+	BatchItemDTO struct {
+		Index      int         `json:"index"`
+		StatusCode int         `json:"status_code"`
+		Contact    *ContactDTO `json:"contact,omitempty"`
+		Created    bool        `json:"created"`
+		Error      string      `json:"error,omitempty"`
+	}
+
+	// OperationDTO is a write a dry run of Identify plans. ContactID is 0 for the contact it would create.
+	OperationDTO struct {
+		Action           string `json:"action"`
+		ContactID        uint   `json:"contact_id"`
+		BeforeLinkedID   uint   `json:"before_linked_id"`
+		AfterLinkedID    uint   `json:"after_linked_id"`
+		BeforePrecedence string `json:"before_precedence"`
+		AfterPrecedence  string `json:"after_precedence"`
+		IdentifierType   string `json:"identifier_type,omitempty"`
+	}
+
+	// MergeRequestDTO ...
+	MergeRequestDTO struct {
+		ContactIDs  []uint `json:"contact_ids"`
+		RequestedBy string `json:"requested_by"`
+		Reason      string `json:"reason"`
+	}
+
+	// MergeResponseDTO ...
+	MergeResponseDTO struct {
+		Contact ContactDTO `json:"contact"`
+		Merged  bool       `json:"merged"`
+	}
+
+	// SplitRequestDTO ...
+	SplitRequestDTO struct {
+		ContactIDs []uint `json:"contact_ids"`
+		Force      bool   `json:"force"`
+	}
+
+	// SplitResponseDTO ...
+	SplitResponseDTO struct {
+		Contact   ContactDTO `json:"contact"`
+		Remaining ContactDTO `json:"remaining"`
+	}
+
+	// ErasureRequestDTO names exactly one of email, phone or contact_id. A contact_id erases its whole cluster.
+	ErasureRequestDTO struct {
+		Email       *string `json:"email"`
+		Phone       *string `json:"phone"`
+		ContactID   uint    `json:"contact_id"`
+		Mode        string  `json:"mode"`
+		RequestedBy string  `json:"requested_by"`
+	}
+
+	// ContactDTO ...
+	ContactDTO struct {
+		PrimaryContactID    uint     `json:"PrimaryContactID"`
+		Emails              []string `json:"emails"`
+		PhoneNumbers        []string `json:"phoneNumbers"`
+		SecondaryContactIds []uint   `json:"secondaryContactIds"`
+		// Identifiers lists the values of every other identifier type, keyed by type.
+		Identifiers map[string][]string `json:"identifiers,omitempty"`
 	}
 )
 
@@ -60,83 +163,617 @@ func (h *LinkIdentityHandler) Identify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contacts, err := h.service.Identify(ctx, model.Email, model.Phone)
+	result, err := h.service.Identify(ctx, application.IdentifyRequest{
+		Identifiers: model.identifierValues(),
+		Observation: model.observation(),
+		DryRun:      model.DryRun,
+	})
+	if isBadRequest(err) {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
 	if err != nil {
 		resp := utils.NewErrorResponse(http.StatusInternalServerError, err.Error())
 		utils.ResponseJSON(w, http.StatusInternalServerError, resp)
 		return
 	}
 
-	resp := utils.ResponseSuccess(http.StatusOK, convertContactsToResponseDTO(contacts))
+	dto := convertContactsToResponseDTO(result.Contacts)
+	dto.Created = result.Created
+	if result.DryRun {
+		dto.DryRun = true
+		dto.Operations = convertOperationsToDTO(result.Operations)
+		dto.NewPrimary = result.NewPrimary
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, dto)
 	utils.ResponseJSON(w, http.StatusOK, resp)
 
 	return
 }
 
-// Validate ...
-func (v *RequestDTO) Validate() *utils.ErrorResponse {
-	if v.Email != "" {
-		if _, err := mail.ParseAddress(v.Email); err != nil {
-			return utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+// IdentifyBatch identifies an array of requests in order and reports the outcome of each of them.
+func (h *LinkIdentityHandler) IdentifyBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var models []*RequestDTO
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&models)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	if len(models) == 0 {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, "the batch cannot be empty")
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	items := make([]BatchItemDTO, len(models))
+	var reqs []application.IdentifyRequest
+	var indexes []int
+	for i, model := range models {
+		items[i].Index = i
+		if model == nil {
+			model = &RequestDTO{}
+		}
+		if v := model.Validate(); v != nil {
+			items[i].StatusCode = v.StatusCode
+			items[i].Error = v.Data.Message
+			continue
+		}
+		reqs = append(reqs, application.IdentifyRequest{
+			Identifiers: model.identifierValues(),
+			Observation: model.observation(),
+			DryRun:      model.DryRun,
+		})
+		indexes = append(indexes, i)
+	}
+
+	results, err := h.service.IdentifyBatch(ctx, reqs)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	for j, result := range results {
+		item := &items[indexes[j]]
+		if result.Err != nil {
+			item.StatusCode = errorStatusCode(result.Err)
+			item.Error = result.Err.Error()
+			continue
+		}
+		dto := convertContactsToResponseDTO(result.Result.Contacts)
+		item.StatusCode = http.StatusOK
+		item.Contact = &dto.Contact
+		item.Created = result.Result.Created
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, items)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Merge ...
+func (h *LinkIdentityHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	model := new(MergeRequestDTO)
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&model)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if v := model.Validate(); v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	result, err := h.service.Merge(ctx, application.MergeRequest{
+		ContactIDs:  model.ContactIDs,
+		RequestedBy: model.RequestedBy,
+		Reason:      model.Reason,
+	})
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	dto := convertContactsToResponseDTO(result.Contacts)
+	resp := utils.ResponseSuccess(http.StatusOK, &MergeResponseDTO{Contact: dto.Contact, Merged: result.Merged})
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// GetContact ...
+func (h *LinkIdentityHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contactID, err := contactIDParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	contacts, err := h.service.GetCluster(ctx, contactID)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, convertContactsToResponseDTO(contacts))
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// FindContact looks a cluster up by a single identifier given as query parameter, such as ?email= or ?phone=.
+func (h *LinkIdentityHandler) FindContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var values []application.IdentifierValue
+	for typ, v := range r.URL.Query() {
+		for _, value := range v {
+			if strings.TrimSpace(value) != "" {
+				values = append(values, application.IdentifierValue{Type: typ, Value: value})
+			}
 		}
 	}
+	if len(values) != 1 {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, "exactly one identifier, such as email or phone, is required")
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
 
-	if v.Phone == "" {
+	contacts, err := h.service.FindCluster(ctx, values[0])
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, convertContactsToResponseDTO(contacts))
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Export streams the consolidated record of every cluster as NDJSON, or as CSV with ?format=csv.
+func (h *LinkIdentityHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = application.ExportFormatNDJSON
+	}
+	writer, err := application.NewClusterWriter(w, format)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	// an export outlives the write timeout of the server.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	written := 0
+	err = h.service.ExportClusters(ctx, func(record *application.ClusterRecord) error {
+		if written == 0 {
+			w.Header().Set("Content-Type", exportContentTypes[format])
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushInterval == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			_ = http.NewResponseController(w).Flush()
+		}
 		return nil
+	})
+	if err != nil && written == 0 {
+		resp := utils.NewErrorResponse(http.StatusInternalServerError, err.Error())
+		utils.ResponseJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	// the status is sent already, so a failure past the first record can only cut the export short.
+	if written == 0 {
+		w.Header().Set("Content-Type", exportContentTypes[format])
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = writer.Flush()
+}
+
+// LinkEvents ...
+func (h *LinkIdentityHandler) LinkEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contactID, err := contactIDParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
 	}
 
-	num, err := phonenumbers.Parse(v.Phone, "")
+	events, err := h.service.LinkEvents(ctx, contactID)
 	if err != nil {
-		return utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
 	}
 
-	if !phonenumbers.IsValidNumber(num) {
-		return utils.NewErrorResponse(http.StatusBadRequest, "Invalid phone number")
+	if events == nil {
+		events = []*domain.LinkEvent{}
 	}
+	resp := utils.ResponseSuccess(http.StatusOK, events)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Observations ...
+func (h *LinkIdentityHandler) Observations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
+	contactID, err := contactIDParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	observations, err := h.service.Observations(ctx, contactID)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if observations == nil {
+		observations = []*domain.Observation{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, observations)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Timeline ...
+func (h *LinkIdentityHandler) Timeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	contactID, err := contactIDParam(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	offset, limit, err := offsetParams(r)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	entries, err := h.service.Timeline(ctx, contactID, offset, limit)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	if entries == nil {
+		entries = []*application.TimelineEntry{}
+	}
+	resp := utils.ResponseSuccess(http.StatusOK, entries)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// offsetParams returns the offset and limit query parameters of a page of a list without ids to page after. Both
+// are 0 when missing.
+func offsetParams(r *http.Request) (int, int, error) {
+	var offset int
+	if v := r.URL.Query().Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = o
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		limit = l
+	}
+	return offset, limit, nil
+}
+
+// contactIDParam returns the contact id of the {id} url parameter.
+func contactIDParam(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 0)
+	if err != nil || id == 0 {
+		return 0, errors.New("contact id must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+// Validate ...
+func (v *MergeRequestDTO) Validate() *utils.ErrorResponse {
+	if len(v.ContactIDs) != 2 {
+		return utils.NewErrorResponse(http.StatusBadRequest, "contact_ids must hold exactly two contact ids")
+	}
+	if v.ContactIDs[0] == v.ContactIDs[1] {
+		return utils.NewErrorResponse(http.StatusBadRequest, "contact_ids must hold two different contact ids")
+	}
+	if strings.TrimSpace(v.RequestedBy) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "requested_by cannot be empty")
+	}
 	return nil
 }
 
-func convertContactsToResponseDTO(contacts []*domain.Contact) *ResponseDTO {
-	var primaryContactID uint
-	var secondaryIds []uint
-	secondaryEmails := make(map[string]bool)
-	secondaryPhones := make(map[string]bool)
-	var primaryEmail string
-	var primaryPhone string
-
-	for _, v := range contacts {
-		if v.LinkedPrecedence == "primary" {
-			primaryContactID = v.ContactID
-			primaryEmail = v.Email.String
-			primaryPhone = v.Phone.String
-		} else {
-			secondaryIds = append(secondaryIds, v.ContactID)
-			secondaryEmails[v.Email.String] = true
-			secondaryPhones[v.Phone.String] = true
+// Split ...
+func (h *LinkIdentityHandler) Split(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	model := new(SplitRequestDTO)
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&model)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if v := model.Validate(); v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	result, err := h.service.Split(ctx, application.SplitRequest{ContactIDs: model.ContactIDs, Force: model.Force})
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, &SplitResponseDTO{
+		Contact:   convertContactsToResponseDTO(result.Contacts).Contact,
+		Remaining: convertContactsToResponseDTO(result.Remaining).Contact,
+	})
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Erase ...
+func (h *LinkIdentityHandler) Erase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	model := new(ErasureRequestDTO)
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&model)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if v := model.Validate(); v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	req := application.ErasureRequest{ContactID: model.ContactID, Mode: model.Mode, RequestedBy: model.RequestedBy}
+	switch {
+	case model.Email != nil:
+		req.Identifier = &application.IdentifierValue{Type: domain.IdentifierTypeEmail, Value: *model.Email}
+	case model.Phone != nil:
+		req.Identifier = &application.IdentifierValue{Type: domain.IdentifierTypePhone, Value: *model.Phone}
+	}
+	receipt, err := h.service.Erase(ctx, req)
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	resp := utils.ResponseSuccess(http.StatusOK, receipt)
+	utils.ResponseJSON(w, http.StatusOK, resp)
+}
+
+// Validate ...
+func (v *ErasureRequestDTO) Validate() *utils.ErrorResponse {
+	var subjects int
+	for _, set := range []bool{v.Email != nil, v.Phone != nil, v.ContactID != 0} {
+		if set {
+			subjects++
 		}
 	}
+	if subjects != 1 {
+		return utils.NewErrorResponse(http.StatusBadRequest, "exactly one of email, phone or contact_id is required")
+	}
+	if v.Mode != "" && v.Mode != domain.ErasureModeSoft && v.Mode != domain.ErasureModeHard {
+		return utils.NewErrorResponse(http.StatusBadRequest, "mode must be soft or hard")
+	}
+	if strings.TrimSpace(v.RequestedBy) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "requested_by cannot be empty")
+	}
+	return nil
+}
 
-	delete(secondaryEmails, primaryEmail)
-	delete(secondaryPhones, primaryPhone)
+// VerifyIdentifier ...
+func (h *LinkIdentityHandler) VerifyIdentifier(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	return &ResponseDTO{Contact: struct {
-		PrimaryContactID    uint     `json:"PrimaryContactID"`
-		Emails              []string `json:"emails"`
-		PhoneNumbers        []string `json:"phoneNumbers"`
-		SecondaryContactIds []uint   `json:"secondaryContactIds"`
-	}{
-		PrimaryContactID:    primaryContactID,
-		Emails:              append([]string{primaryEmail}, convertMapToArray(secondaryEmails)...),
-		PhoneNumbers:        append([]string{primaryPhone}, convertMapToArray(secondaryPhones)...),
-		SecondaryContactIds: secondaryIds,
-	}}
+	model := new(VerifyRequestDTO)
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&model)
+	if err != nil {
+		resp := utils.NewErrorResponse(http.StatusBadRequest, err.Error())
+		utils.ResponseJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	if v := model.Validate(); v != nil {
+		utils.ResponseJSON(w, v.StatusCode, v)
+		return
+	}
+
+	err = h.service.VerifyIdentifier(ctx, application.VerifyRequest{
+		ContactID:  model.ContactID,
+		Identifier: application.IdentifierValue{Type: model.Type, Value: model.Value},
+	})
+	if err != nil {
+		statusCode := errorStatusCode(err)
+		resp := utils.NewErrorResponse(statusCode, err.Error())
+		utils.ResponseJSON(w, statusCode, resp)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Validate ...
+func (v *VerifyRequestDTO) Validate() *utils.ErrorResponse {
+	if v.ContactID == 0 {
+		return utils.NewErrorResponse(http.StatusBadRequest, "contact_id is required")
+	}
+	if strings.TrimSpace(v.Type) == "" || strings.TrimSpace(v.Value) == "" {
+		return utils.NewErrorResponse(http.StatusBadRequest, "type and value are required")
+	}
+	return nil
+}
+
+// Validate ...
+func (v *SplitRequestDTO) Validate() *utils.ErrorResponse {
+	if len(v.ContactIDs) == 0 {
+		return utils.NewErrorResponse(http.StatusBadRequest, "contact_ids cannot be empty")
+	}
+	return nil
+}
+
+// Validate ...
+func (v *RequestDTO) Validate() *utils.ErrorResponse {
+	if len(v.identifierValues()) == 0 {
+		return utils.NewErrorResponse(http.StatusBadRequest, "at least one of email, phone or identifiers is required")
+	}
+	for _, i := range v.Identifiers {
+		if strings.TrimSpace(i.Type) == "" {
+			return utils.NewErrorResponse(http.StatusBadRequest, "identifier type cannot be empty")
+		}
+	}
+
+	// values are validated by the service, which knows how each identifier type is normalized.
+	return nil
+}
+
+// identifierValues returns the email, the phone and the other identifiers of the request, leaving out the missing
+// and blank ones.
+func (v *RequestDTO) identifierValues() []application.IdentifierValue {
+	var values []application.IdentifierValue
+	if v.Email != nil && strings.TrimSpace(*v.Email) != "" {
+		values = append(values, application.IdentifierValue{
+			Type:     domain.IdentifierTypeEmail,
+			Value:    *v.Email,
+			Verified: v.EmailVerified,
+		})
+	}
+	if v.Phone != nil && strings.TrimSpace(*v.Phone) != "" {
+		values = append(values, application.IdentifierValue{
+			Type:     domain.IdentifierTypePhone,
+			Value:    *v.Phone,
+			Verified: v.PhoneVerified,
+		})
+	}
+	for _, i := range v.Identifiers {
+		if strings.TrimSpace(i.Value) != "" {
+			values = append(values, application.IdentifierValue{Type: i.Type, Value: i.Value, Verified: i.Verified})
+		}
+	}
+	return values
 }
 
-func convertMapToArray(m map[string]bool) []string {
-	var arr []string
-	for k := range m {
-		arr = append(arr, k)
+// observation returns where the identifiers of the request were seen, or nil when the request does not say.
+func (v *RequestDTO) observation() *application.Observation {
+	if v.Source == "" && v.ExternalOrderID == "" && v.OccurredAt == nil && len(v.Metadata) == 0 {
+		return nil
+	}
+	return &application.Observation{
+		Source:          v.Source,
+		ExternalOrderID: v.ExternalOrderID,
+		OccurredAt:      v.OccurredAt,
+		Metadata:        v.Metadata,
 	}
-	return arr
+}
+
+// isBadRequest reports whether the service rejected the request itself rather than failed to handle it.
+func isBadRequest(err error) bool {
+	return errors.Is(err, application.ErrMissingIdentifier) ||
+		errors.Is(err, application.ErrUnknownIdentifierType) ||
+		errors.Is(err, application.ErrInvalidIdentifier) ||
+		errors.Is(err, application.ErrInvalidMergeRequest) ||
+		errors.Is(err, application.ErrInvalidSplitRequest) ||
+		errors.Is(err, application.ErrInvalidErasureRequest) ||
+		errors.Is(err, application.ErrInvalidDenylistEntry) ||
+		errors.Is(err, application.ErrInvalidWebhookSubscription) ||
+		errors.Is(err, application.ErrInvalidObservation) ||
+		errors.Is(err, application.ErrBatchTooLarge)
+}
+
+// errorStatusCode maps an error of the service to the status code of the response.
+func errorStatusCode(err error) int {
+	switch {
+	case isBadRequest(err):
+		return http.StatusBadRequest
+	case errors.Is(err, application.ErrContactNotFound),
+		errors.Is(err, application.ErrIdentifierNotFound),
+		errors.Is(err, application.ErrDenylistEntryNotFound),
+		errors.Is(err, application.ErrWebhookSubscriptionNotFound),
+		errors.Is(err, application.ErrWebhookDeadLetterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, application.ErrSplitConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func convertOperationsToDTO(operations []*domain.LinkEvent) []OperationDTO {
+	dtos := make([]OperationDTO, 0, len(operations))
+	for _, o := range operations {
+		dtos = append(dtos, OperationDTO{
+			Action:           o.Action,
+			ContactID:        o.ContactID,
+			BeforeLinkedID:   o.BeforeLinkedID,
+			AfterLinkedID:    o.AfterLinkedID,
+			BeforePrecedence: o.BeforePrecedence,
+			AfterPrecedence:  o.AfterPrecedence,
+			IdentifierType:   o.IdentifierType,
+		})
+	}
+	return dtos
+}
+
+// convertContactsToResponseDTO consolidates the contacts of a cluster as application.NewClusterRecord does.
+func convertContactsToResponseDTO(contacts []*domain.Contact) *ResponseDTO {
+	record := application.NewClusterRecord(contacts)
+	return &ResponseDTO{Contact: ContactDTO{
+		PrimaryContactID:    record.PrimaryContactID,
+		Emails:              record.Emails,
+		PhoneNumbers:        record.PhoneNumbers,
+		SecondaryContactIds: record.SecondaryContactIDs,
+		Identifiers:         record.Identifiers,
+	}}
 }
@@ -3,16 +3,18 @@ package http_test
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/link-identity/app/application"
 	"github.com/link-identity/app/domain"
 	httpHandler "github.com/link-identity/app/http"
 	mockObject "github.com/link-identity/app/mock"
 
+	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -35,8 +37,8 @@ func TestLinkIdentityHandler_Identify(t *testing.T) {
 		{
 			Name: "Happy path",
 			RequestPayload: &httpHandler.RequestDTO{
-				Email: "test1@gmail.com",
-				Phone: "+4917611111111",
+				Email: stringPtr("test1@gmail.com"),
+				Phone: stringPtr("+4917611111111"),
 			},
 			ExpectedResponse: `{
 				"status_code": 200,
@@ -50,22 +52,230 @@ func TestLinkIdentityHandler_Identify(t *testing.T) {
 							"+4917611111111"
 						],
 						"secondaryContactIds": null
-					}
+					},
+					"created": true
     			}}`,
 			Service: testStruct{
 				IsCalled: true,
-				Response: []*domain.Contact{
-					{
-						ContactID:        1,
-						Email:            sql.NullString{String: "test1@gmail.com", Valid: true},
-						Phone:            sql.NullString{String: "+4917611111111", Valid: true},
-						LinkedPrecedence: "primary",
+				Response: &application.IdentifyResult{
+					Contacts: []*domain.Contact{
+						{
+							ContactID: 1,
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeEmail, Value: "test1@gmail.com", RawValue: "test1@gmail.com"},
+								{Type: domain.IdentifierTypePhone, Value: "+4917611111111", RawValue: "+4917611111111"},
+							},
+							LinkedPrecedence: "primary",
+						},
 					},
+					Created: true,
 				},
 				Error: nil,
 			},
 			ExpectedStatusCode: http.StatusOK,
 		},
+		{
+			Name: "Phone only",
+			RequestPayload: &httpHandler.RequestDTO{
+				Phone: stringPtr("+4917611111111"),
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.IdentifyResult{
+					Contacts: []*domain.Contact{
+						{
+							ContactID: 1,
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypePhone, Value: "+4917611111111", RawValue: "+4917611111111"},
+							},
+							LinkedPrecedence: "primary",
+						},
+					},
+					Created: true,
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name: "Other identifier types",
+			RequestPayload: &httpHandler.RequestDTO{
+				Identifiers: []httpHandler.IdentifierDTO{
+					{Type: domain.IdentifierTypeLoyaltyID, Value: "fk-1985"},
+				},
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.IdentifyResult{
+					Contacts: []*domain.Contact{
+						{
+							ContactID: 1,
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeLoyaltyID, Value: "FK-1985", RawValue: "fk-1985"},
+							},
+							LinkedPrecedence: "primary",
+						},
+					},
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name: "Dry run",
+			RequestPayload: &httpHandler.RequestDTO{
+				Email:  stringPtr("test2@gmail.com"),
+				DryRun: true,
+			},
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": {
+					"contact": {
+						"PrimaryContactID": 1,
+						"emails": ["test1@gmail.com", "test2@gmail.com"],
+						"phoneNumbers": [],
+						"secondaryContactIds": null
+					},
+					"created": true,
+					"dry_run": true,
+					"operations": [{
+						"action": "create",
+						"contact_id": 0,
+						"before_linked_id": 0,
+						"after_linked_id": 1,
+						"before_precedence": "",
+						"after_precedence": "secondary",
+						"identifier_type": "email"
+					}]
+				}}`,
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.IdentifyResult{
+					Contacts: []*domain.Contact{
+						{
+							ContactID:        1,
+							LinkedPrecedence: "primary",
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeEmail, Value: "test1@gmail.com", RawValue: "test1@gmail.com"},
+							},
+						},
+						{
+							LinkedID:         1,
+							LinkedPrecedence: "secondary",
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeEmail, Value: "test2@gmail.com", RawValue: "test2@gmail.com"},
+							},
+						},
+					},
+					Created: true,
+					Operations: []*domain.LinkEvent{
+						{
+							Action: domain.LinkActionCreate, AfterLinkedID: 1, AfterPrecedence: "secondary",
+							IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "test2@gmail.com",
+						},
+					},
+					DryRun: true,
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name: "Dry run with a new primary",
+			RequestPayload: &httpHandler.RequestDTO{
+				Email:  stringPtr("test2@gmail.com"),
+				DryRun: true,
+			},
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": {
+					"contact": {
+						"PrimaryContactID": 0,
+						"emails": ["test2@gmail.com", "test1@gmail.com"],
+						"phoneNumbers": [],
+						"secondaryContactIds": [1]
+					},
+					"created": true,
+					"dry_run": true,
+					"operations": [{
+						"action": "create",
+						"contact_id": 0,
+						"before_linked_id": 0,
+						"after_linked_id": 0,
+						"before_precedence": "",
+						"after_precedence": "primary",
+						"identifier_type": "email"
+					}, {
+						"action": "demote",
+						"contact_id": 1,
+						"before_linked_id": 0,
+						"after_linked_id": 0,
+						"before_precedence": "primary",
+						"after_precedence": "secondary",
+						"identifier_type": "email"
+					}],
+					"new_primary": true
+				}}`,
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.IdentifyResult{
+					Contacts: []*domain.Contact{
+						{
+							ContactID:        1,
+							LinkedPrecedence: "secondary",
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeEmail, Value: "test1@gmail.com", RawValue: "test1@gmail.com"},
+							},
+						},
+						{
+							LinkedPrecedence: "primary",
+							Identifiers: []*domain.Identifier{
+								{Type: domain.IdentifierTypeEmail, Value: "test2@gmail.com", RawValue: "test2@gmail.com"},
+							},
+						},
+					},
+					Created: true,
+					Operations: []*domain.LinkEvent{
+						{
+							Action: domain.LinkActionCreate, AfterPrecedence: "primary",
+							IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "test2@gmail.com",
+						},
+						{
+							ContactID: 1, Action: domain.LinkActionDemote,
+							BeforePrecedence: "primary", AfterPrecedence: "secondary",
+							IdentifierType: domain.IdentifierTypeEmail, IdentifierValue: "test2@gmail.com",
+						},
+					},
+					DryRun:     true,
+					NewPrimary: true,
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name: "Invalid observation",
+			RequestPayload: &httpHandler.RequestDTO{
+				Email:           stringPtr("test1@gmail.com"),
+				ExternalOrderID: "ORD-1985",
+				Metadata:        json.RawMessage(`["app"]`),
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrInvalidObservation,
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name: "Identifier without a type",
+			RequestPayload: &httpHandler.RequestDTO{
+				Identifiers: []httpHandler.IdentifierDTO{{Value: "fk-1985"}},
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name: "Neither email nor phone",
+			RequestPayload: &httpHandler.RequestDTO{
+				Email: stringPtr(" "),
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -74,9 +284,9 @@ func TestLinkIdentityHandler_Identify(t *testing.T) {
 			serviceMock := new(mockObject.LinkIdentityServiceMock)
 			if tt.Service.IsCalled {
 				if tt.Service.Response == nil {
-					tt.Service.Response = ([]*domain.Contact)(nil)
+					tt.Service.Response = (*application.IdentifyResult)(nil)
 				}
-				serviceMock.On("Identify", ctx, mock.Anything, mock.Anything).
+				serviceMock.On("Identify", ctx, mock.Anything).
 					Return(tt.Service.Response, tt.Service.Error)
 			}
 
@@ -98,9 +308,764 @@ func TestLinkIdentityHandler_Identify(t *testing.T) {
 			AuthorizedCustomerHandler := http.HandlerFunc(handler.Identify)
 			AuthorizedCustomerHandler.ServeHTTP(rr, req)
 
-			var body map[string]interface{}
-			json.NewDecoder(rr.Body).Decode(&body)
 			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			if tt.ExpectedResponse != "" {
+				assert.JSONEq(t, tt.ExpectedResponse, rr.Body.String())
+			}
+		})
+	}
+}
+
+// TestLinkIdentityHandler_IdentifyBatch ...
+func TestLinkIdentityHandler_IdentifyBatch(t *testing.T) {
+	ctx := context.Background()
+
+	serviceMock := new(mockObject.LinkIdentityServiceMock)
+	serviceMock.On("IdentifyBatch", ctx, []application.IdentifyRequest{
+		{Identifiers: []application.IdentifierValue{{Type: domain.IdentifierTypeEmail, Value: "test1@gmail.com"}}},
+		{Identifiers: []application.IdentifierValue{{Type: domain.IdentifierTypePhone, Value: "12"}}},
+	}).Return([]application.BatchItemResult{
+		{Result: &application.IdentifyResult{
+			Contacts: []*domain.Contact{{ContactID: 1, LinkedPrecedence: "primary"}},
+			Created:  true,
+		}},
+		{Err: application.ErrInvalidIdentifier},
+	}, nil).Once()
+
+	handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+	payload := `[{"email": "test1@gmail.com"}, {"email": " "}, {"phone": "12"}]`
+	req, err := http.NewRequest("POST", "/identify/batch", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handler.IdentifyBatch).ServeHTTP(rr, req)
+
+	var body struct {
+		Data []httpHandler.BatchItemDTO `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body.Data, 3)
+	assert.Equal(t, http.StatusOK, body.Data[0].StatusCode)
+	assert.Equal(t, uint(1), body.Data[0].Contact.PrimaryContactID)
+	assert.Equal(t, http.StatusBadRequest, body.Data[1].StatusCode)
+	assert.Equal(t, 2, body.Data[2].Index)
+	assert.Equal(t, http.StatusBadRequest, body.Data[2].StatusCode)
+	assert.Nil(t, body.Data[2].Contact)
+	serviceMock.AssertExpectations(t)
+}
+
+// TestLinkIdentityHandler_Merge ...
+func TestLinkIdentityHandler_Merge(t *testing.T) {
+	tests := []struct {
+		Name               string
+		RequestPayload     *httpHandler.MergeRequestDTO
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name: "Happy path",
+			RequestPayload: &httpHandler.MergeRequestDTO{
+				ContactIDs: []uint{1, 3}, RequestedBy: "agent-7", Reason: "same customer",
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.MergeResult{
+					Contacts: []*domain.Contact{
+						{ContactID: 1, LinkedPrecedence: "primary"},
+						{ContactID: 3, LinkedID: 1, LinkedPrecedence: "secondary"},
+					},
+					Merged: true,
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name: "Unknown contact",
+			RequestPayload: &httpHandler.MergeRequestDTO{
+				ContactIDs: []uint{1, 9}, RequestedBy: "agent-7",
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrContactNotFound,
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name: "Single contact",
+			RequestPayload: &httpHandler.MergeRequestDTO{
+				ContactIDs: []uint{1}, RequestedBy: "agent-7",
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name: "Missing requester",
+			RequestPayload: &httpHandler.MergeRequestDTO{
+				ContactIDs: []uint{1, 3},
+			},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = (*application.MergeResult)(nil)
+				}
+				serviceMock.On("Merge", ctx, mock.Anything).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+			jsonPayload, _ := json.Marshal(tt.RequestPayload)
+			req, err := http.NewRequest("POST", "/contacts/merge", bytes.NewBuffer(jsonPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handler.Merge).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_Split ...
+func TestLinkIdentityHandler_Split(t *testing.T) {
+	tests := []struct {
+		Name               string
+		RequestPayload     *httpHandler.SplitRequestDTO
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:           "Happy path",
+			RequestPayload: &httpHandler.SplitRequestDTO{ContactIDs: []uint{3}},
+			Service: testStruct{
+				IsCalled: true,
+				Response: &application.SplitResult{
+					Contacts:  []*domain.Contact{{ContactID: 3, LinkedPrecedence: "primary"}},
+					Remaining: []*domain.Contact{{ContactID: 1, LinkedPrecedence: "primary"}},
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:           "Shared identifiers",
+			RequestPayload: &httpHandler.SplitRequestDTO{ContactIDs: []uint{2}},
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrSplitConflict,
+			},
+			ExpectedStatusCode: http.StatusConflict,
+		},
+		{
+			Name:               "No contacts",
+			RequestPayload:     &httpHandler.SplitRequestDTO{},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = (*application.SplitResult)(nil)
+				}
+				serviceMock.On("Split", ctx, mock.Anything).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+			jsonPayload, _ := json.Marshal(tt.RequestPayload)
+			req, err := http.NewRequest("POST", "/contacts/split", bytes.NewBuffer(jsonPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handler.Split).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_Erase ...
+func TestLinkIdentityHandler_Erase(t *testing.T) {
+	tests := []struct {
+		Name               string
+		RequestPayload     *httpHandler.ErasureRequestDTO
+		ExpectedRequest    application.ErasureRequest
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:           "Erase an email",
+			RequestPayload: &httpHandler.ErasureRequestDTO{Email: stringPtr("biff@hillvalley.edu"), Mode: "hard", RequestedBy: "dpo"},
+			ExpectedRequest: application.ErasureRequest{
+				Identifier:  &application.IdentifierValue{Type: domain.IdentifierTypeEmail, Value: "biff@hillvalley.edu"},
+				Mode:        "hard",
+				RequestedBy: "dpo",
+			},
+			Service: testStruct{
+				IsCalled: true,
+				Response: &domain.ErasureReceipt{ReceiptID: 1, Mode: "hard", Scope: "email"},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "Unknown cluster",
+			RequestPayload:     &httpHandler.ErasureRequestDTO{ContactID: 9, RequestedBy: "dpo"},
+			ExpectedRequest:    application.ErasureRequest{ContactID: 9, RequestedBy: "dpo"},
+			Service:            testStruct{IsCalled: true, Error: application.ErrContactNotFound},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Email and contact",
+			RequestPayload:     &httpHandler.ErasureRequestDTO{Email: stringPtr("biff@hillvalley.edu"), ContactID: 3, RequestedBy: "dpo"},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "Unknown mode",
+			RequestPayload:     &httpHandler.ErasureRequestDTO{ContactID: 3, Mode: "shred", RequestedBy: "dpo"},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "No requester",
+			RequestPayload:     &httpHandler.ErasureRequestDTO{ContactID: 3},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = (*domain.ErasureReceipt)(nil)
+				}
+				serviceMock.On("Erase", ctx, tt.ExpectedRequest).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+			jsonPayload, _ := json.Marshal(tt.RequestPayload)
+			req, err := http.NewRequest("POST", "/contacts/erase", bytes.NewBuffer(jsonPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handler.Erase).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_VerifyIdentifier ...
+func TestLinkIdentityHandler_VerifyIdentifier(t *testing.T) {
+	tests := []struct {
+		Name               string
+		RequestPayload     *httpHandler.VerifyRequestDTO
+		ServiceError       error
+		IsCalled           bool
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "Verify a phone",
+			RequestPayload:     &httpHandler.VerifyRequestDTO{ContactID: 1, Type: "phone", Value: "+4917611111111"},
+			IsCalled:           true,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "Identifier the contact does not carry",
+			RequestPayload:     &httpHandler.VerifyRequestDTO{ContactID: 1, Type: "phone", Value: "+4917622222222"},
+			ServiceError:       application.ErrIdentifierNotFound,
+			IsCalled:           true,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Invalid identifier",
+			RequestPayload:     &httpHandler.VerifyRequestDTO{ContactID: 1, Type: "phone", Value: "12"},
+			ServiceError:       application.ErrInvalidIdentifier,
+			IsCalled:           true,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "No contact",
+			RequestPayload:     &httpHandler.VerifyRequestDTO{Type: "phone", Value: "+4917611111111"},
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.IsCalled {
+				serviceMock.On("VerifyIdentifier", ctx, application.VerifyRequest{
+					ContactID:  tt.RequestPayload.ContactID,
+					Identifier: application.IdentifierValue{Type: tt.RequestPayload.Type, Value: tt.RequestPayload.Value},
+				}).Return(tt.ServiceError)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+			jsonPayload, _ := json.Marshal(tt.RequestPayload)
+			req, err := http.NewRequest("POST", "/identifiers/verify", bytes.NewBuffer(jsonPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handler.VerifyIdentifier).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_LinkEvents ...
+func TestLinkIdentityHandler_LinkEvents(t *testing.T) {
+	tests := []struct {
+		Name               string
+		ContactID          string
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:      "Happy path",
+			ContactID: "3",
+			Service: testStruct{
+				IsCalled: true,
+				Response: []*domain.LinkEvent{
+					{EventID: 1, ContactID: 3, Action: domain.LinkActionCreate, AfterPrecedence: "primary"},
+					{EventID: 2, ContactID: 3, Action: domain.LinkActionDemote, AfterLinkedID: 1},
+				},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:      "Unknown contact",
+			ContactID: "9",
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrContactNotFound,
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Invalid contact id",
+			ContactID:          "abc",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = ([]*domain.LinkEvent)(nil)
+				}
+				serviceMock.On("LinkEvents", mock.Anything, mock.Anything).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/contacts/{id}/events", handler.LinkEvents)
+
+			req, err := http.NewRequest("GET", "/contacts/"+tt.ContactID+"/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_Observations ...
+func TestLinkIdentityHandler_Observations(t *testing.T) {
+	occurredAt := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name               string
+		ContactID          string
+		ExpectedResponse   string
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:      "Happy path",
+			ContactID: "3",
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": [{
+					"observation_id": 7,
+					"contact_id": 3,
+					"source": "web-shop",
+					"external_order_id": "ORD-1985",
+					"occurred_at": "2023-04-01T00:00:00Z",
+					"metadata": {"channel": "app"},
+					"created_at": "2023-04-01T00:00:00Z"
+				}]
+			}`,
+			Service: testStruct{
+				IsCalled: true,
+				Response: []*domain.Observation{{
+					ObservationID:   7,
+					ContactID:       3,
+					Source:          "web-shop",
+					ExternalOrderID: "ORD-1985",
+					OccurredAt:      occurredAt,
+					Metadata:        json.RawMessage(`{"channel":"app"}`),
+					CreatedAt:       occurredAt,
+				}},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:      "Contact without observations",
+			ContactID: "3",
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": []
+			}`,
+			Service:            testStruct{IsCalled: true},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:      "Unknown contact",
+			ContactID: "9",
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrContactNotFound,
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Invalid contact id",
+			ContactID:          "abc",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = ([]*domain.Observation)(nil)
+				}
+				serviceMock.On("Observations", mock.Anything, mock.Anything).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/contacts/{id}/observations", handler.Observations)
+
+			req, err := http.NewRequest("GET", "/contacts/"+tt.ContactID+"/observations", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			if tt.ExpectedResponse != "" {
+				assert.JSONEq(t, tt.ExpectedResponse, rr.Body.String())
+			}
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_GetContact ...
+func TestLinkIdentityHandler_GetContact(t *testing.T) {
+	cluster := []*domain.Contact{
+		{
+			ContactID:        1,
+			LinkedPrecedence: "primary",
+			Identifiers: []*domain.Identifier{
+				{Type: domain.IdentifierTypeEmail, Value: "test1@gmail.com", RawValue: "test1@gmail.com"},
+			},
+		},
+		{ContactID: 2, LinkedID: 1, LinkedPrecedence: "secondary"},
+	}
+
+	tests := []struct {
+		Name               string
+		URL                string
+		ServiceMethod      string
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:               "By id",
+			URL:                "/contacts/2",
+			ServiceMethod:      "GetCluster",
+			Service:            testStruct{IsCalled: true, Response: cluster},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "By email",
+			URL:                "/contacts?email=test1@gmail.com",
+			ServiceMethod:      "FindCluster",
+			Service:            testStruct{IsCalled: true, Response: cluster},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "Unknown phone",
+			URL:                "/contacts?phone=%2B4917699999999",
+			ServiceMethod:      "FindCluster",
+			Service:            testStruct{IsCalled: true, Error: application.ErrContactNotFound},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Email and phone",
+			URL:                "/contacts?email=test1@gmail.com&phone=%2B4917611111111",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "Invalid id",
+			URL:                "/contacts/0",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = ([]*domain.Contact)(nil)
+				}
+				serviceMock.On(tt.ServiceMethod, mock.Anything, mock.Anything).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/contacts", handler.FindContact)
+			router.Get("/contacts/{id}", handler.GetContact)
+
+			req, err := http.NewRequest("GET", tt.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			serviceMock.AssertExpectations(t)
+		})
+	}
+}
+
+// TestLinkIdentityHandler_Export ...
+func TestLinkIdentityHandler_Export(t *testing.T) {
+	serviceMock := new(mockObject.LinkIdentityServiceMock)
+	serviceMock.On("ExportClusters", mock.Anything).Return([]*application.ClusterRecord{
+		{PrimaryContactID: 1, Emails: []string{"test1@gmail.com"}, PhoneNumbers: []string{}},
+		{PrimaryContactID: 3, Emails: []string{}, PhoneNumbers: []string{"+4917611111111"}, SecondaryContactIDs: []uint{4}},
+	}, nil).Once()
+
+	handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+
+	req, err := http.NewRequest("GET", "/export?format=csv", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handler.Export).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "primary_contact_id,emails,phone_numbers,secondary_contact_ids,identifiers\n"+
+		"1,test1@gmail.com,,,\n"+
+		"3,,+4917611111111,4,\n", rr.Body.String())
+	serviceMock.AssertExpectations(t)
+
+	req, err = http.NewRequest("GET", "/export?format=xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handler.Export).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// TestLinkIdentityHandler_Timeline ...
+func TestLinkIdentityHandler_Timeline(t *testing.T) {
+	createdAt := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name               string
+		ContactID          string
+		Query              string
+		ExpectedOffset     int
+		ExpectedLimit      int
+		ExpectedResponse   string
+		ExpectedStatusCode int
+		Service            testStruct
+	}{
+		{
+			Name:           "Happy path",
+			ContactID:      "3",
+			Query:          "?offset=10&limit=2",
+			ExpectedOffset: 10,
+			ExpectedLimit:  2,
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": [{
+					"at": "2023-04-01T00:00:00Z",
+					"kind": "identifier_first_seen",
+					"contact_id": 3,
+					"identifier_type": "email",
+					"identifier_value": "mcfly@hillvalley.edu",
+					"observation_id": 7,
+					"source": "web-shop",
+					"external_order_id": "ORD-1985"
+				}, {
+					"at": "2023-04-01T01:00:00Z",
+					"kind": "merged",
+					"contact_id": 3,
+					"action": "demote",
+					"linked_id": 1,
+					"precedence": "secondary",
+					"event_id": 12
+				}]
+			}`,
+			Service: testStruct{
+				IsCalled: true,
+				Response: []*application.TimelineEntry{{
+					At:              createdAt,
+					Kind:            application.TimelineIdentifierFirstSeen,
+					ContactID:       3,
+					IdentifierType:  domain.IdentifierTypeEmail,
+					IdentifierValue: "mcfly@hillvalley.edu",
+					ObservationID:   7,
+					Source:          "web-shop",
+					ExternalOrderID: "ORD-1985",
+				}, {
+					At:         createdAt.Add(time.Hour),
+					Kind:       application.TimelineMerged,
+					ContactID:  3,
+					Action:     domain.LinkActionDemote,
+					LinkedID:   1,
+					Precedence: "secondary",
+					EventID:    12,
+				}},
+			},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:      "Page past the end of the timeline",
+			ContactID: "3",
+			Query:     "?offset=100",
+			ExpectedResponse: `{
+				"status_code": 200,
+				"data": []
+			}`,
+			ExpectedOffset:     100,
+			Service:            testStruct{IsCalled: true},
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:      "Unknown contact",
+			ContactID: "9",
+			Service: testStruct{
+				IsCalled: true,
+				Error:    application.ErrContactNotFound,
+			},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "Negative offset",
+			ContactID:          "3",
+			Query:              "?offset=-1",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "Invalid limit",
+			ContactID:          "3",
+			Query:              "?limit=0",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "Invalid contact id",
+			ContactID:          "abc",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serviceMock := new(mockObject.LinkIdentityServiceMock)
+			if tt.Service.IsCalled {
+				if tt.Service.Response == nil {
+					tt.Service.Response = ([]*application.TimelineEntry)(nil)
+				}
+				serviceMock.On("Timeline", mock.Anything, mock.Anything, tt.ExpectedOffset, tt.ExpectedLimit).
+					Return(tt.Service.Response, tt.Service.Error)
+			}
+
+			handler := httpHandler.NewLinkIdentityHandler(serviceMock)
+			router := chi.NewRouter()
+			router.Get("/contacts/{id}/timeline", handler.Timeline)
+
+			req, err := http.NewRequest("GET", "/contacts/"+tt.ContactID+"/timeline"+tt.Query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rr.Code)
+			if tt.ExpectedResponse != "" {
+				assert.JSONEq(t, tt.ExpectedResponse, rr.Body.String())
+			}
+			serviceMock.AssertExpectations(t)
 		})
 	}
 }
@@ -279,6 +279,9 @@ var (
 	// ContextKeyRequestID ...
 	ContextKeyRequestID = &contextKey{"request-id"}
 
+	// ContextKeyTenantID ...
+	ContextKeyTenantID = &contextKey{"tenant-id"}
+
 	// ContextKeyRequestIP ...
 	ContextKeyRequestIP = &contextKey{"request-ip"}
 
@@ -0,0 +1,32 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogPublisher publishes outbox events by logging them. It is the default publisher until the events are sent to
+// a broker.
+type LogPublisher struct {
+	logEntry *logrus.Entry
+}
+
+// NewLogPublisher ...
+func NewLogPublisher(logEntry *logrus.Entry) *LogPublisher {
+	return &LogPublisher{logEntry: logEntry}
+}
+
+// Publish ...
+func (p *LogPublisher) Publish(_ context.Context, event *domain.OutboxEvent) error {
+	p.logEntry.WithFields(logrus.Fields{
+		"event_id":   event.EventID,
+		"event_type": event.Type,
+		"contact_id": event.ContactID,
+		"request_id": event.RequestID,
+		"payload":    event.Payload,
+	}).Info("domain event published")
+	return nil
+}
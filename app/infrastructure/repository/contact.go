@@ -2,22 +2,85 @@ package repository
 
 import (
 	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"sort"
+	"time"
 
 	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
 	"github.com/link-identity/app/infrastructure/sql"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrSerializationFailure is returned by WithTransaction when the database aborted the transaction because of a
+// conflicting concurrent transaction. The transaction can safely be retried.
+var ErrSerializationFailure = errors.New("[Repository] transaction aborted by a concurrent transaction")
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+type txContextKey struct{}
+
 // ContactRepository ...
+// Every method reads and writes the rows of the tenant carried by the context only, see infrastructure.TenantID.
+// The exceptions are the claims of outbox events and webhook deliveries, which serve every tenant, and the
+// methods updating a claimed row by its id.
 type ContactRepository interface {
-	GetContactByEmail(ctx context.Context, email string) (*domain.Contact, error)
-	GetContactByPhone(ctx context.Context, phone string) (*domain.Contact, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error
+	WithLockedTransaction(ctx context.Context, keys []string, fn func(ctx context.Context) error) error
+	GetContactsByIdentifiers(ctx context.Context, keys []domain.IdentifierKey) ([]*domain.Contact, error)
+	GetContactWithIdentifiers(ctx context.Context, keys []domain.IdentifierKey) (*domain.Contact, error)
+	GetContactsByLinkedIDs(ctx context.Context, ids []uint) ([]*domain.Contact, error)
 	GetAllContacts(ctx context.Context) ([]*domain.Contact, error)
+	ListContacts(ctx context.Context, afterID uint, limit int) ([]*domain.Contact, error)
+	ListPrimaryContacts(ctx context.Context, afterID uint, limit int) ([]*domain.Contact, error)
 	GetAllSecondaryContacts(ctx context.Context, linkedID uint) ([]*domain.Contact, error)
 	GetPrimaryContactFromLinkedID(ctx context.Context, linkedID uint) (*domain.Contact, error)
 	CreateContact(ctx context.Context, contact *domain.Contact) (*domain.Contact, error)
 	UpdateContact(ctx context.Context, contact *domain.Contact) (*domain.Contact, error)
+	VerifyIdentifier(ctx context.Context, contactID uint, key domain.IdentifierKey, at time.Time) (bool, error)
+	UpdateIdentifierValue(ctx context.Context, identifierID uint, value string) error
+	CreateContactMerge(ctx context.Context, merge *domain.ContactMerge) error
+	CreateLinkEvents(ctx context.Context, events []*domain.LinkEvent) error
+	GetLinkEventsByContactIDs(ctx context.Context, ids []uint) ([]*domain.LinkEvent, error)
+	CreateObservation(ctx context.Context, observation *domain.Observation) error
+	GetObservationsByContactIDs(ctx context.Context, ids []uint) ([]*domain.Observation, error)
+	DeleteContacts(ctx context.Context, ids []uint, hard bool) error
+	DeleteIdentifiers(ctx context.Context, identifierIDs []uint, hard bool) error
+	ScrubLinkEvents(ctx context.Context, keys []domain.IdentifierKey) error
+	CreateErasureReceipt(ctx context.Context, receipt *domain.ErasureReceipt) error
+	CountContactsByIdentifiers(ctx context.Context, keys []domain.IdentifierKey) (map[domain.IdentifierKey]int, error)
+	GetFlaggedIdentifiers(ctx context.Context, keys []domain.IdentifierKey) ([]*domain.FlaggedIdentifier, error)
+	ListFlaggedIdentifiers(ctx context.Context, afterID uint, limit int) ([]*domain.FlaggedIdentifier, error)
+	CreateFlaggedIdentifiers(ctx context.Context, flagged []*domain.FlaggedIdentifier) error
+	ListDenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error)
+	GetDenylistEntry(ctx context.Context, id uint) (*domain.DenylistEntry, error)
+	CreateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error
+	UpdateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error
+	DeleteDenylistEntry(ctx context.Context, id uint) (bool, error)
+	CreateOutboxEvents(ctx context.Context, events []*domain.OutboxEvent) error
+	ClaimOutboxEvents(ctx context.Context, limit int) ([]*domain.OutboxEvent, error)
+	MarkOutboxEventsPublished(ctx context.Context, ids []uint, at time.Time) error
+	CreateWebhookSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id uint) (bool, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*domain.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, due, leaseUntil time.Time, limit int) ([]*domain.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	DeleteWebhookDelivery(ctx context.Context, id uint) error
+	CreateWebhookDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error
+	ListWebhookDeadLetters(ctx context.Context, afterID uint, limit int) ([]*domain.WebhookDeadLetter, error)
+	GetWebhookDeadLetter(ctx context.Context, id uint) (*domain.WebhookDeadLetter, error)
+	MarkWebhookDeadLetterReplayed(ctx context.Context, id uint, at time.Time) error
 }
 
 type contactDBRepo struct {
@@ -31,25 +94,175 @@ func NewContactRepository(db *sql.DbConn) ContactRepository {
 	}
 }
 
-func (r *contactDBRepo) GetContactByEmail(ctx context.Context, email string) (*domain.Contact, error) {
-	db := r.db.GormConn
-	contact := &domain.Contact{}
-	rows := db.WithContext(ctx).Where("email = ?", email).Find(contact)
-	if rows.Error != nil {
-		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by email")
+// WithTransaction runs fn inside a serializable transaction. Repository calls made with the context handed to fn
+// join that transaction; calling WithTransaction again with it does not open a nested one.
+func (r *contactDBRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return fn(ctx)
 	}
-	if rows.RowsAffected == 0 {
+
+	err := r.db.GormConn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	}, &stdsql.TxOptions{Isolation: stdsql.LevelSerializable})
+	return translateTxError(err)
+}
+
+// WithSavepoint runs fn inside a savepoint of the transaction carried by ctx. When fn fails, only the changes it
+// made are rolled back and the transaction can go on. Without a transaction in ctx it runs fn in a new one.
+func (r *contactDBRepo) WithSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, ok := ctx.Value(txContextKey{}).(*gorm.DB)
+	if !ok {
+		return r.WithTransaction(ctx, fn)
+	}
+
+	// gorm runs a transaction started on a transaction in a savepoint.
+	err := tx.WithContext(ctx).Transaction(func(sp *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, sp))
+	})
+	return translateTxError(err)
+}
+
+// translateTxError returns ErrSerializationFailure for errors telling that a transaction conflicted with a
+// concurrent one.
+func translateTxError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode) {
+		return errors.WithMessage(ErrSerializationFailure, err.Error())
+	}
+	return err
+}
+
+// WithLockedTransaction runs fn like WithTransaction while holding an advisory lock per key, taken per tenant.
+//
+// The locks are taken on the connection of the transaction before it begins, not inside it: a serializable
+// transaction reads from a snapshot taken by its first statement, so a lock taken by that statement would be granted
+// after the snapshot, and the waiting transaction would still miss the changes of the one it waited for and fail
+// with a serialization error. Taken before, a transaction waiting for a lock reads the graph as the holder committed
+// it, so calls sharing an identifier run one after the other. Calls that only meet further along the graph are
+// still kept apart by the serializable isolation. Keys are locked in sorted order so that two transactions locking
+// overlapping keys cannot deadlock each other, and the locks are released once the transaction ended. When ctx
+// carries a transaction already, fn joins it and the locks are left to the caller that opened it.
+func (r *contactDBRepo) WithLockedTransaction(
+	ctx context.Context,
+	keys []string,
+	fn func(ctx context.Context) error,
+) error {
+	if _, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return r.db.GormConn.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		defer unlockIdentifiers(ctx, conn)
+		if err := lockIdentifiers(ctx, conn, keys); err != nil {
+			return err
+		}
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txContextKey{}, tx))
+		}, &stdsql.TxOptions{Isolation: stdsql.LevelSerializable})
+		return translateTxError(err)
+	})
+}
+
+// lockIdentifiers takes a session scoped advisory lock per key on the connection.
+func lockIdentifiers(ctx context.Context, conn *gorm.DB, keys []string) error {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	tenantID := infrastructure.TenantID(ctx)
+	for i, key := range sorted {
+		if i > 0 && key == sorted[i-1] {
+			continue
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(tenantID + "/" + key))
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", int64(h.Sum64())).Error; err != nil {
+			return errors.Wrapf(translateTxError(err), "[Repository] error while locking identifier %s", key)
+		}
+	}
+	return nil
+}
+
+// unlockIdentifiers releases the advisory locks of the connection, even once ctx is done. A connection that cannot
+// release them is closed rather than returned to the pool still holding them.
+func unlockIdentifiers(ctx context.Context, conn *gorm.DB) {
+	if conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock_all()").Error == nil {
+		return
+	}
+	if c, ok := conn.Statement.ConnPool.(*stdsql.Conn); ok {
+		_ = c.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+}
+
+// conn returns the transaction carried by ctx, or the connection pool when there is none.
+func (r *contactDBRepo) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.db.GormConn.WithContext(ctx)
+}
+
+// scoped returns conn restricted to the rows of the tenant carried by ctx. Unlike a plain condition, the
+// restriction is kept by every query built from it.
+func (r *contactDBRepo) scoped(ctx context.Context) *gorm.DB {
+	tenant := clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+		Value:  infrastructure.TenantID(ctx),
+	}
+	return r.conn(ctx).Where(tenant).Session(&gorm.Session{})
+}
+
+// contacts returns scoped for queries on contacts, which are always read together with their identifiers.
+func (r *contactDBRepo) contacts(ctx context.Context) *gorm.DB {
+	return r.scoped(ctx).Preload("Identifiers")
+}
+
+// GetContactsByIdentifiers returns every contact that carries one of the given identifiers.
+func (r *contactDBRepo) GetContactsByIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) ([]*domain.Contact, error) {
+	if len(keys) == 0 {
 		return nil, nil
 	}
-	return contact, nil
+
+	db := r.contacts(ctx)
+	matching := r.scoped(ctx).
+		Model(&domain.Identifier{}).
+		Select("contact_id").
+		Where("(type, value) IN ?", keyPairs(keys))
+	var contacts []*domain.Contact
+	rows := db.Where("contact_id IN (?)", matching).Find(&contacts)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by identifiers")
+	}
+	return contacts, nil
 }
 
-func (r *contactDBRepo) GetContactByPhone(ctx context.Context, phone string) (*domain.Contact, error) {
-	db := r.db.GormConn
+// GetContactWithIdentifiers returns the oldest contact carrying exactly the given identifiers and no other one, or
+// nil when there is none.
+func (r *contactDBRepo) GetContactWithIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) (*domain.Contact, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	db := r.contacts(ctx)
+	carrying := r.scoped(ctx).
+		Model(&domain.Identifier{}).
+		Select("contact_id").
+		Where("(type, value) IN ?", keyPairs(keys))
+	matching := r.scoped(ctx).
+		Model(&domain.Identifier{}).
+		Select("contact_id").
+		Where("contact_id IN (?)", carrying).
+		Group("contact_id").
+		Having("COUNT(*) = ? AND COUNT(*) FILTER (WHERE (type, value) IN ?) = ?", len(keys), keyPairs(keys), len(keys))
 	contact := &domain.Contact{}
-	rows := db.WithContext(ctx).Where("phone = ?", phone).Find(contact)
+	rows := db.Where("contact_id IN (?)", matching).Order("created_at, contact_id").Limit(1).Find(contact)
 	if rows.Error != nil {
-		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by phone")
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contact with identifiers")
 	}
 	if rows.RowsAffected == 0 {
 		return nil, nil
@@ -57,10 +270,24 @@ func (r *contactDBRepo) GetContactByPhone(ctx context.Context, phone string) (*d
 	return contact, nil
 }
 
+// GetContactsByLinkedIDs returns the contacts with the given ids as well as every contact linked to one of them.
+func (r *contactDBRepo) GetContactsByLinkedIDs(ctx context.Context, ids []uint) ([]*domain.Contact, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	db := r.contacts(ctx)
+	var contacts []*domain.Contact
+	rows := db.Where("contact_id IN ? OR linked_id IN ?", ids, ids).Find(&contacts)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by linked ids")
+	}
+	return contacts, nil
+}
+
 func (r *contactDBRepo) GetAllContacts(ctx context.Context) ([]*domain.Contact, error) {
-	db := r.db.GormConn
+	db := r.contacts(ctx)
 	var contacts []*domain.Contact
-	rows := db.WithContext(ctx).Find(contacts)
+	rows := db.Find(&contacts)
 	if rows.Error != nil {
 		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting all contacts")
 	}
@@ -70,10 +297,36 @@ func (r *contactDBRepo) GetAllContacts(ctx context.Context) ([]*domain.Contact,
 	return contacts, nil
 }
 
+// ListContacts returns up to limit contacts with an id greater than afterID, ordered by id.
+func (r *contactDBRepo) ListContacts(ctx context.Context, afterID uint, limit int) ([]*domain.Contact, error) {
+	db := r.contacts(ctx)
+	var contacts []*domain.Contact
+	rows := db.Where("contact_id > ?", afterID).Order("contact_id").Limit(limit).Find(&contacts)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing contacts")
+	}
+	return contacts, nil
+}
+
+// ListPrimaryContacts returns up to limit primary contacts with an id greater than afterID, ordered by id.
+func (r *contactDBRepo) ListPrimaryContacts(ctx context.Context, afterID uint, limit int) ([]*domain.Contact, error) {
+	db := r.contacts(ctx)
+	var contacts []*domain.Contact
+	rows := db.
+		Where("linked_precedence = ? AND contact_id > ?", "primary", afterID).
+		Order("contact_id").
+		Limit(limit).
+		Find(&contacts)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing primary contacts")
+	}
+	return contacts, nil
+}
+
 func (r *contactDBRepo) GetAllSecondaryContacts(ctx context.Context, linkedID uint) ([]*domain.Contact, error) {
-	db := r.db.GormConn
+	db := r.contacts(ctx)
 	var contacts []*domain.Contact
-	rows := db.WithContext(ctx).Where("linked_id = ? OR contact_id = ?", linkedID, linkedID).Find(&contacts)
+	rows := db.Where("linked_id = ? OR contact_id = ?", linkedID, linkedID).Find(&contacts)
 	if rows != nil && rows.Error != nil {
 		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by linked_id")
 	}
@@ -83,10 +336,12 @@ func (r *contactDBRepo) GetAllSecondaryContacts(ctx context.Context, linkedID ui
 	return contacts, nil
 }
 
+// GetPrimaryContactFromLinkedID returns the contact with the given id, which is the primary of the contacts
+// linked to it. It returns nil when there is no such contact.
 func (r *contactDBRepo) GetPrimaryContactFromLinkedID(ctx context.Context, linkedID uint) (*domain.Contact, error) {
-	db := r.db.GormConn
-	var contact *domain.Contact
-	rows := db.WithContext(ctx).Where("contact_id = ?", linkedID).Find(contact)
+	db := r.contacts(ctx)
+	contact := &domain.Contact{}
+	rows := db.Where("contact_id = ?", linkedID).Find(contact)
 	if rows != nil && rows.Error != nil {
 		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting contacts by contact_id")
 	}
@@ -96,20 +351,557 @@ func (r *contactDBRepo) GetPrimaryContactFromLinkedID(ctx context.Context, linke
 	return contact, nil
 }
 
+// CreateContact inserts the contact together with its identifiers.
 func (r *contactDBRepo) CreateContact(ctx context.Context, contact *domain.Contact) (*domain.Contact, error) {
-	db := r.db.GormConn
-	rows := db.WithContext(ctx).Create(contact)
+	contact.TenantID = infrastructure.TenantID(ctx)
+	for _, i := range contact.Identifiers {
+		i.TenantID = contact.TenantID
+	}
+	db := r.scoped(ctx)
+	rows := db.Create(contact)
 	if rows != nil && rows.Error != nil {
 		return nil, errors.Wrapf(rows.Error, "[Repository] error while creating a contact")
 	}
 	return contact, nil
 }
 
+// UpdateContact only updates the link of the contact. Its identifiers are left untouched.
 func (r *contactDBRepo) UpdateContact(ctx context.Context, contact *domain.Contact) (*domain.Contact, error) {
-	db := r.db.GormConn
-	rows := db.WithContext(ctx).Where("contact_id = ?", contact.ContactID).Updates(contact)
+	db := r.scoped(ctx)
+	rows := db.
+		Select("linked_id", "linked_precedence").
+		Where("contact_id = ?", contact.ContactID).
+		Updates(contact)
 	if rows != nil && rows.Error != nil {
-		return nil, errors.Wrapf(rows.Error, "[Repository] error while creating a contact")
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while updating a contact")
 	}
 	return contact, nil
 }
+
+// UpdateIdentifierValue rewrites the normalized value of an identifier.
+func (r *contactDBRepo) UpdateIdentifierValue(ctx context.Context, identifierID uint, value string) error {
+	db := r.scoped(ctx)
+	rows := db.Model(&domain.Identifier{}).Where("identifier_id = ?", identifierID).Update("value", value)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while updating an identifier")
+	}
+	return nil
+}
+
+// CreateContactMerge records a merge requested by hand.
+func (r *contactDBRepo) CreateContactMerge(ctx context.Context, merge *domain.ContactMerge) error {
+	merge.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(merge)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while recording a contact merge")
+	}
+	return nil
+}
+
+// CreateLinkEvents appends the events to the link change log.
+func (r *contactDBRepo) CreateLinkEvents(ctx context.Context, events []*domain.LinkEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, e := range events {
+		e.TenantID = infrastructure.TenantID(ctx)
+	}
+	db := r.scoped(ctx)
+	rows := db.Create(events)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while recording link events")
+	}
+	return nil
+}
+
+// GetLinkEventsByContactIDs returns the link events of the given contacts in the order they were recorded.
+func (r *contactDBRepo) GetLinkEventsByContactIDs(ctx context.Context, ids []uint) ([]*domain.LinkEvent, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	db := r.scoped(ctx)
+	var events []*domain.LinkEvent
+	rows := db.Where("contact_id IN ?", ids).Order("event_id").Find(&events)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting link events")
+	}
+	return events, nil
+}
+
+// CreateObservation ...
+func (r *contactDBRepo) CreateObservation(ctx context.Context, observation *domain.Observation) error {
+	observation.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(observation)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while recording an observation")
+	}
+	return nil
+}
+
+// GetObservationsByContactIDs returns the observations of the given contacts in the order they were recorded.
+func (r *contactDBRepo) GetObservationsByContactIDs(ctx context.Context, ids []uint) ([]*domain.Observation, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	db := r.scoped(ctx)
+	var observations []*domain.Observation
+	rows := db.Where("contact_id IN ?", ids).Order("observation_id").Find(&observations)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting observations")
+	}
+	return observations, nil
+}
+
+// DeleteContacts deletes the contacts together with their identifiers. Unless hard is set, the rows are only
+// marked as deleted. A hard delete also deletes their observations, whose metadata may hold personal data.
+func (r *contactDBRepo) DeleteContacts(ctx context.Context, ids []uint, hard bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db := r.scoped(ctx)
+	if hard {
+		db = db.Unscoped().Session(&gorm.Session{})
+	} else {
+		rows := db.Model(&domain.Contact{}).Where("contact_id IN ?", ids).Update("deleted", true)
+		if rows.Error != nil {
+			return errors.Wrapf(rows.Error, "[Repository] error while marking contacts as deleted")
+		}
+	}
+
+	rows := db.Where("contact_id IN ?", ids).Delete(&domain.Identifier{})
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while deleting identifiers of contacts")
+	}
+	rows = db.Where("contact_id IN ?", ids).Delete(&domain.Contact{})
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while deleting contacts")
+	}
+	if hard {
+		rows = db.Where("contact_id IN ?", ids).Delete(&domain.Observation{})
+		if rows.Error != nil {
+			return errors.Wrapf(rows.Error, "[Repository] error while deleting observations of contacts")
+		}
+	}
+	return nil
+}
+
+// DeleteIdentifiers deletes the identifiers. Unless hard is set, the rows are only marked as deleted.
+func (r *contactDBRepo) DeleteIdentifiers(ctx context.Context, identifierIDs []uint, hard bool) error {
+	if len(identifierIDs) == 0 {
+		return nil
+	}
+	db := r.scoped(ctx)
+	if hard {
+		db = db.Unscoped().Session(&gorm.Session{})
+	}
+	rows := db.Where("identifier_id IN ?", identifierIDs).Delete(&domain.Identifier{})
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while deleting identifiers")
+	}
+	return nil
+}
+
+// ScrubLinkEvents clears the identifier value of every link event caused by one of the identifiers.
+func (r *contactDBRepo) ScrubLinkEvents(ctx context.Context, keys []domain.IdentifierKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	db := r.scoped(ctx)
+	rows := db.Model(&domain.LinkEvent{}).
+		Where("(identifier_type, identifier_value) IN ?", keyPairs(keys)).
+		Update("identifier_value", "")
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while scrubbing link events")
+	}
+	return nil
+}
+
+// CreateErasureReceipt ...
+func (r *contactDBRepo) CreateErasureReceipt(ctx context.Context, receipt *domain.ErasureReceipt) error {
+	receipt.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(receipt)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while recording an erasure receipt")
+	}
+	return nil
+}
+
+// CountContactsByIdentifiers returns the number of distinct contacts carrying each of the identifiers. Identifiers
+// no contact carries are left out.
+func (r *contactDBRepo) CountContactsByIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) (map[domain.IdentifierKey]int, error) {
+	counts := make(map[domain.IdentifierKey]int, len(keys))
+	if len(keys) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		Type     string
+		Value    string
+		Contacts int
+	}
+	db := r.scoped(ctx)
+	err := db.Model(&domain.Identifier{}).
+		Select("type, value, COUNT(DISTINCT contact_id) AS contacts").
+		Where("(type, value) IN ?", keyPairs(keys)).
+		Group("type, value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "[Repository] error while counting contacts by identifiers")
+	}
+	for _, row := range rows {
+		counts[domain.IdentifierKey{Type: row.Type, Value: row.Value}] = row.Contacts
+	}
+	return counts, nil
+}
+
+// GetFlaggedIdentifiers returns the identifiers among keys that are flagged.
+func (r *contactDBRepo) GetFlaggedIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) ([]*domain.FlaggedIdentifier, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	db := r.scoped(ctx)
+	var flagged []*domain.FlaggedIdentifier
+	rows := db.Where("(type, value) IN ?", keyPairs(keys)).Find(&flagged)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting flagged identifiers")
+	}
+	return flagged, nil
+}
+
+// ListFlaggedIdentifiers returns up to limit flagged identifiers with an id greater than afterID, in id order.
+func (r *contactDBRepo) ListFlaggedIdentifiers(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.FlaggedIdentifier, error) {
+	db := r.scoped(ctx)
+	var flagged []*domain.FlaggedIdentifier
+	rows := db.Where("flagged_identifier_id > ?", afterID).
+		Order("flagged_identifier_id").
+		Limit(limit).
+		Find(&flagged)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing flagged identifiers")
+	}
+	return flagged, nil
+}
+
+// CreateFlaggedIdentifiers flags the identifiers. Identifiers flagged already, for instance by a concurrent
+// transaction, are left as they are.
+func (r *contactDBRepo) CreateFlaggedIdentifiers(ctx context.Context, flagged []*domain.FlaggedIdentifier) error {
+	if len(flagged) == 0 {
+		return nil
+	}
+	for _, f := range flagged {
+		f.TenantID = infrastructure.TenantID(ctx)
+	}
+	db := r.scoped(ctx)
+	rows := db.Clauses(clause.OnConflict{DoNothing: true}).Create(flagged)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while flagging identifiers")
+	}
+	return nil
+}
+
+// VerifyIdentifier marks the identifier of the contact as verified at the given time and reports whether the
+// contact carries it.
+func (r *contactDBRepo) VerifyIdentifier(
+	ctx context.Context,
+	contactID uint,
+	key domain.IdentifierKey,
+	at time.Time,
+) (bool, error) {
+	db := r.scoped(ctx)
+	rows := db.Model(&domain.Identifier{}).
+		Where("contact_id = ? AND type = ? AND value = ?", contactID, key.Type, key.Value).
+		Update("verified_at", at)
+	if rows.Error != nil {
+		return false, errors.Wrapf(rows.Error, "[Repository] error while verifying identifier")
+	}
+	return rows.RowsAffected > 0, nil
+}
+
+// ListDenylistEntries returns every denylist entry in id order.
+func (r *contactDBRepo) ListDenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error) {
+	db := r.scoped(ctx)
+	var entries []*domain.DenylistEntry
+	rows := db.Order("entry_id").Find(&entries)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing denylist entries")
+	}
+	return entries, nil
+}
+
+// GetDenylistEntry returns the denylist entry with the given id, or nil if there is none.
+func (r *contactDBRepo) GetDenylistEntry(ctx context.Context, id uint) (*domain.DenylistEntry, error) {
+	db := r.scoped(ctx)
+	var entries []*domain.DenylistEntry
+	rows := db.Where("entry_id = ?", id).Limit(1).Find(&entries)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting denylist entry")
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return entries[0], nil
+}
+
+// CreateDenylistEntry ...
+func (r *contactDBRepo) CreateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error {
+	entry.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(entry)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while creating denylist entry")
+	}
+	return nil
+}
+
+// UpdateDenylistEntry ...
+func (r *contactDBRepo) UpdateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error {
+	db := r.scoped(ctx)
+	rows := db.Model(entry).Select("type", "match", "value", "reason").Updates(entry)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while updating denylist entry")
+	}
+	return nil
+}
+
+// DeleteDenylistEntry deletes the denylist entry and reports whether it existed.
+func (r *contactDBRepo) DeleteDenylistEntry(ctx context.Context, id uint) (bool, error) {
+	db := r.scoped(ctx)
+	rows := db.Where("entry_id = ?", id).Delete(&domain.DenylistEntry{})
+	if rows.Error != nil {
+		return false, errors.Wrapf(rows.Error, "[Repository] error while deleting denylist entry")
+	}
+	return rows.RowsAffected > 0, nil
+}
+
+// keyPairs returns the keys as (type, value) pairs for an IN condition.
+func keyPairs(keys []domain.IdentifierKey) [][]interface{} {
+	pairs := make([][]interface{}, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, []interface{}{key.Type, key.Value})
+	}
+	return pairs
+}
+
+// CreateOutboxEvents appends the events to the outbox.
+func (r *contactDBRepo) CreateOutboxEvents(ctx context.Context, events []*domain.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, e := range events {
+		e.TenantID = infrastructure.TenantID(ctx)
+	}
+	db := r.scoped(ctx)
+	rows := db.Create(events)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while creating outbox events")
+	}
+	return nil
+}
+
+// ClaimOutboxEvents returns up to limit unpublished events of every tenant in id order and locks them until the
+// transaction of ctx ends. Events locked by another dispatcher are skipped, so it must be called from within
+// WithTransaction.
+func (r *contactDBRepo) ClaimOutboxEvents(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	db := r.conn(ctx)
+	var events []*domain.OutboxEvent
+	rows := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL").
+		Order("event_id").
+		Limit(limit).
+		Find(&events)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while claiming outbox events")
+	}
+	return events, nil
+}
+
+// MarkOutboxEventsPublished sets the time the events were published at.
+func (r *contactDBRepo) MarkOutboxEventsPublished(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db := r.conn(ctx)
+	rows := db.Model(&domain.OutboxEvent{}).Where("event_id IN ?", ids).Update("published_at", at)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while marking outbox events published")
+	}
+	return nil
+}
+
+// CreateWebhookSubscription ...
+func (r *contactDBRepo) CreateWebhookSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	subscription.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(subscription)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while creating webhook subscription")
+	}
+	return nil
+}
+
+// ListWebhookSubscriptions returns every webhook subscription in id order.
+func (r *contactDBRepo) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	db := r.scoped(ctx)
+	var subscriptions []*domain.WebhookSubscription
+	rows := db.Order("subscription_id").Find(&subscriptions)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing webhook subscriptions")
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription deletes the webhook subscription with the deliveries still pending for it and reports
+// whether it existed. Its dead letters are kept.
+func (r *contactDBRepo) DeleteWebhookSubscription(ctx context.Context, id uint) (bool, error) {
+	db := r.scoped(ctx)
+	rows := db.Where("subscription_id = ? AND delivered_at IS NULL", id).Delete(&domain.WebhookDelivery{})
+	if rows.Error != nil {
+		return false, errors.Wrapf(rows.Error, "[Repository] error while deleting webhook deliveries")
+	}
+	rows = db.Where("subscription_id = ?", id).Delete(&domain.WebhookSubscription{})
+	if rows.Error != nil {
+		return false, errors.Wrapf(rows.Error, "[Repository] error while deleting webhook subscription")
+	}
+	return rows.RowsAffected > 0, nil
+}
+
+// CreateWebhookDeliveries ...
+func (r *contactDBRepo) CreateWebhookDeliveries(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	for _, d := range deliveries {
+		d.TenantID = infrastructure.TenantID(ctx)
+	}
+	db := r.scoped(ctx)
+	rows := db.Create(deliveries)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while creating webhook deliveries")
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries of every tenant due at the given time, the earliest
+// first, and leases them until leaseUntil by moving their next attempt there. Deliveries locked by another deliverer
+// are skipped, so it must be called from within WithTransaction; once that is committed, the deliveries can be sent
+// without holding it, and no other deliverer claims them before the lease runs out.
+func (r *contactDBRepo) ClaimWebhookDeliveries(
+	ctx context.Context,
+	due time.Time,
+	leaseUntil time.Time,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	db := r.conn(ctx)
+	var deliveries []*domain.WebhookDelivery
+	rows := db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("delivered_at IS NULL AND next_attempt_at <= ?", due).
+		Order("next_attempt_at, delivery_id").
+		Limit(limit).
+		Find(&deliveries)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while claiming webhook deliveries")
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]uint, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.DeliveryID)
+		d.NextAttemptAt = leaseUntil
+	}
+	rows = db.Model(&domain.WebhookDelivery{}).Where("delivery_id IN ?", ids).Update("next_attempt_at", leaseUntil)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while leasing webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery stores the outcome of an attempt.
+func (r *contactDBRepo) UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	db := r.conn(ctx)
+	rows := db.Model(delivery).
+		Select("attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		Updates(delivery)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while updating webhook delivery")
+	}
+	return nil
+}
+
+// DeleteWebhookDelivery ...
+func (r *contactDBRepo) DeleteWebhookDelivery(ctx context.Context, id uint) error {
+	db := r.conn(ctx)
+	rows := db.Where("delivery_id = ?", id).Delete(&domain.WebhookDelivery{})
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while deleting webhook delivery")
+	}
+	return nil
+}
+
+// CreateWebhookDeadLetter ...
+func (r *contactDBRepo) CreateWebhookDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error {
+	letter.TenantID = infrastructure.TenantID(ctx)
+	db := r.scoped(ctx)
+	rows := db.Create(letter)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while creating webhook dead letter")
+	}
+	return nil
+}
+
+// ListWebhookDeadLetters returns up to limit dead letters with an id greater than afterID, in id order.
+func (r *contactDBRepo) ListWebhookDeadLetters(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.WebhookDeadLetter, error) {
+	db := r.scoped(ctx)
+	var letters []*domain.WebhookDeadLetter
+	rows := db.Where("dead_letter_id > ?", afterID).
+		Order("dead_letter_id").
+		Limit(limit).
+		Find(&letters)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while listing webhook dead letters")
+	}
+	return letters, nil
+}
+
+// GetWebhookDeadLetter returns the dead letter with the given id, or nil if there is none.
+func (r *contactDBRepo) GetWebhookDeadLetter(ctx context.Context, id uint) (*domain.WebhookDeadLetter, error) {
+	db := r.scoped(ctx)
+	var letters []*domain.WebhookDeadLetter
+	rows := db.Where("dead_letter_id = ?", id).Limit(1).Find(&letters)
+	if rows.Error != nil {
+		return nil, errors.Wrapf(rows.Error, "[Repository] error while getting webhook dead letter")
+	}
+	if len(letters) == 0 {
+		return nil, nil
+	}
+	return letters[0], nil
+}
+
+// MarkWebhookDeadLetterReplayed sets the time the dead letter was replayed at.
+func (r *contactDBRepo) MarkWebhookDeadLetterReplayed(ctx context.Context, id uint, at time.Time) error {
+	db := r.scoped(ctx)
+	rows := db.Model(&domain.WebhookDeadLetter{}).Where("dead_letter_id = ?", id).Update("replayed_at", at)
+	if rows.Error != nil {
+		return errors.Wrapf(rows.Error, "[Repository] error while marking webhook dead letter replayed")
+	}
+	return nil
+}
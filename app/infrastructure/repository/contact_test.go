@@ -0,0 +1,219 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+	"github.com/link-identity/app/infrastructure/repository"
+	"github.com/link-identity/app/infrastructure/sql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newRepository returns a repository on a mocked database.
+func newRepository(t *testing.T) (repository.ContactRepository, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository.NewContactRepository(&sql.DbConn{GormConn: db}), mock
+}
+
+// TestContactRepository_GetContactWithIdentifiers ...
+func TestContactRepository_GetContactWithIdentifiers(t *testing.T) {
+	ctx := infrastructure.WithTenantID(context.Background(), "shop-a")
+	repo, mock := newRepository(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "contact" WHERE "contact"."tenant_id" = $1 AND contact_id IN `+
+		`(SELECT "contact_id" FROM "contact_identifier" WHERE "contact_identifier"."tenant_id" = $2 AND contact_id IN `+
+		`(SELECT "contact_id" FROM "contact_identifier" WHERE "contact_identifier"."tenant_id" = $3 `+
+		`AND (type, value) IN (($4,$5)) AND "contact_identifier"."deleted_at" IS NULL) `+
+		`AND "contact_identifier"."deleted_at" IS NULL GROUP BY "contact_id" `+
+		`HAVING COUNT(*) = $6 AND COUNT(*) FILTER (WHERE (type, value) IN (($7,$8))) = $9) `+
+		`AND "contact"."deleted_at" IS NULL ORDER BY created_at, contact_id LIMIT $10`)).
+		WithArgs("shop-a", "shop-a", "shop-a", "phone", "+4930123456789", 1, "phone", "+4930123456789", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"contact_id", "linked_precedence"}).AddRow(4, "primary"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "contact_identifier" WHERE "contact_identifier"."contact_id" = $1`)).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"identifier_id", "contact_id", "type", "value"}).
+			AddRow(7, 4, "phone", "+4930123456789"))
+
+	contact, err := repo.GetContactWithIdentifiers(ctx, []domain.IdentifierKey{
+		{Type: domain.IdentifierTypePhone, Value: "+4930123456789"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(4), contact.ContactID)
+	assert.Len(t, contact.Identifiers, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestContactRepository_DeleteContacts ...
+func TestContactRepository_DeleteContacts(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Hard   bool
+		Expect func(mock sqlmock.Sqlmock)
+	}{
+		{
+			Name: "Hard delete removes the identifiers, contacts and observations",
+			Hard: true,
+			Expect: func(mock sqlmock.Sqlmock) {
+				for _, table := range []string{"contact_identifier", "contact", "contact_observation"} {
+					mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "`+table+`" WHERE "`+table+`"."tenant_id" = $1 `+
+						`AND contact_id IN ($2,$3)`)).
+						WithArgs("shop-a", 2, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+				}
+			},
+		},
+		{
+			Name: "Soft delete marks the contacts and their identifiers as deleted",
+			Expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "contact" SET "deleted"=$1,"updated_at"=$2 `+
+					`WHERE "contact"."tenant_id" = $3 AND contact_id IN ($4,$5) AND "contact"."deleted_at" IS NULL`)).
+					WithArgs(true, sqlmock.AnyArg(), "shop-a", 2, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+				for _, table := range []string{"contact_identifier", "contact"} {
+					mock.ExpectExec(regexp.QuoteMeta(`UPDATE "`+table+`" SET "deleted_at"=$1 `+
+						`WHERE "`+table+`"."tenant_id" = $2 AND contact_id IN ($3,$4) AND "`+table+`"."deleted_at" IS NULL`)).
+						WithArgs(sqlmock.AnyArg(), "shop-a", 2, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := infrastructure.WithTenantID(context.Background(), "shop-a")
+			repo, mock := newRepository(t)
+			tt.Expect(mock)
+
+			err := repo.DeleteContacts(ctx, []uint{2, 3}, tt.Hard)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestContactRepository_DeleteIdentifiers ...
+func TestContactRepository_DeleteIdentifiers(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Hard   bool
+		Expect func(mock sqlmock.Sqlmock)
+	}{
+		{
+			Name: "Hard delete removes the identifiers",
+			Hard: true,
+			Expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "contact_identifier" `+
+					`WHERE "contact_identifier"."tenant_id" = $1 AND identifier_id IN ($2,$3)`)).
+					WithArgs("shop-a", 7, 8).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			Name: "Soft delete marks the identifiers as deleted",
+			Expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "contact_identifier" SET "deleted_at"=$1 `+
+					`WHERE "contact_identifier"."tenant_id" = $2 AND identifier_id IN ($3,$4) `+
+					`AND "contact_identifier"."deleted_at" IS NULL`)).
+					WithArgs(sqlmock.AnyArg(), "shop-a", 7, 8).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := infrastructure.WithTenantID(context.Background(), "shop-a")
+			repo, mock := newRepository(t)
+			tt.Expect(mock)
+
+			err := repo.DeleteIdentifiers(ctx, []uint{7, 8}, tt.Hard)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestContactRepository_ClaimWebhookDeliveries ...
+func TestContactRepository_ClaimWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	repo, mock := newRepository(t)
+	due := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	leaseUntil := due.Add(time.Minute)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "webhook_delivery" WHERE delivered_at IS NULL `+
+		`AND next_attempt_at <= $1 ORDER BY next_attempt_at, delivery_id LIMIT $2 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(due, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "tenant_id", "next_attempt_at"}).
+			AddRow(5, "shop-a", due).AddRow(6, "shop-b", due))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_delivery" SET "next_attempt_at"=$1 WHERE delivery_id IN ($2,$3)`)).
+		WithArgs(leaseUntil, 5, 6).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deliveries, err := repo.ClaimWebhookDeliveries(ctx, due, leaseUntil, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+	for _, d := range deliveries {
+		assert.Equal(t, leaseUntil, d.NextAttemptAt)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestContactRepository_WithLockedTransaction ...
+func TestContactRepository_WithLockedTransaction(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Err   error
+		Setup func(mock sqlmock.Sqlmock)
+	}{
+		{
+			Name: "Locks are taken before the transaction begins and released once it committed",
+			Setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectCommit()
+			},
+		},
+		{
+			Name: "Locks are released once the transaction rolled back",
+			Err:  errors.New("failed"),
+			Setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ctx := infrastructure.WithTenantID(context.Background(), "shop-a")
+			repo, mock := newRepository(t)
+			lock := regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)
+			mock.ExpectExec(lock).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lock).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectBegin()
+			tt.Setup(mock)
+			mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock_all()`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			keys := []string{"phone:+4930123456789", "email:doc@hillvalley.edu", "phone:+4930123456789"}
+			err := repo.WithLockedTransaction(ctx, keys, func(ctx context.Context) error {
+				return tt.Err
+			})
+
+			assert.Equal(t, tt.Err, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
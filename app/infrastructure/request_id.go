@@ -0,0 +1,47 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// HeaderRequestID is the header a request ID is read from and echoed in.
+const HeaderRequestID string = "X-Request-ID"
+
+type requestIDMiddleware struct{}
+
+// NewRequestIDMiddleware returns a middleware that stores the request ID of every request in its context under
+// ContextKeyRequestID. Requests without an X-Request-ID header are given a random one.
+func NewRequestIDMiddleware() Middleware {
+	return &requestIDMiddleware{}
+}
+
+// Wrap ...
+func (m *requestIDMiddleware) Wrap(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		ctx := context.WithValue(r.Context(), ContextKeyRequestID, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string when there is none.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(ContextKeyRequestID).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
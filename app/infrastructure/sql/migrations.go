@@ -5,6 +5,7 @@ import (
 
 	"github.com/link-identity/app/domain"
 
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
@@ -12,9 +13,90 @@ import (
 func RunMigrations(db *gorm.DB) {
 	m := []interface{}{
 		&domain.Contact{},
+		&domain.Identifier{},
+		&domain.ContactMerge{},
+		&domain.LinkEvent{},
+		&domain.ErasureReceipt{},
+		&domain.FlaggedIdentifier{},
+		&domain.DenylistEntry{},
+		&domain.OutboxEvent{},
+		&domain.WebhookSubscription{},
+		&domain.WebhookDelivery{},
+		&domain.WebhookDeadLetter{},
+		&domain.Observation{},
 	}
 	err := db.AutoMigrate(m...)
 	if err != nil {
 		log.Fatalf("error while connecting to the database %s", err)
 	}
+	dropTenantlessIndexes(db)
+	migrateLegacyIdentifiers(db)
+}
+
+// dropTenantlessIndexes drops the indexes on identifiers that were replaced by indexes leading with the tenant.
+// The unique index on flagged identifiers would otherwise stop two tenants from flagging the same identifier.
+func dropTenantlessIndexes(db *gorm.DB) {
+	indexes := []struct {
+		model interface{}
+		name  string
+	}{
+		{model: &domain.Identifier{}, name: "idx_contact_identifier_type_value"},
+		{model: &domain.FlaggedIdentifier{}, name: "idx_flagged_identifier_type_value"},
+	}
+	for _, i := range indexes {
+		if !db.Migrator().HasIndex(i.model, i.name) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(i.model, i.name); err != nil {
+			log.Fatalf("error while dropping the index %s %s", i.name, err)
+		}
+	}
+}
+
+// migrateLegacyIdentifiers copies the email and phone columns contacts had before identifiers were typed into
+// contact_identifier, then drops the columns. Dropping them makes the copy run once: an identifier erased later
+// must not come back from a legacy column on the next start. Phones keep their stored value; the backfill command
+// normalizes them.
+func migrateLegacyIdentifiers(db *gorm.DB) {
+	legacy := []struct {
+		column, typ, value string
+	}{
+		{column: "email", typ: domain.IdentifierTypeEmail, value: "LOWER(c.email)"},
+		{column: "phone", typ: domain.IdentifierTypePhone, value: "c.phone"},
+	}
+	if db.Migrator().HasColumn(&domain.Contact{}, "email_canonical") {
+		legacy[0].value = "COALESCE(c.email_canonical, LOWER(c.email))"
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, l := range legacy {
+			if !tx.Migrator().HasColumn(&domain.Contact{}, l.column) {
+				continue
+			}
+			err := tx.Exec(`
+				INSERT INTO contact_identifier (contact_id, type, value, raw_value, created_at, updated_at)
+				SELECT c.contact_id, ?, `+l.value+`, c.`+l.column+`, c.created_at, c.updated_at
+				FROM contact c
+				WHERE c.`+l.column+` IS NOT NULL AND c.`+l.column+` <> ''
+				AND NOT EXISTS (
+					SELECT 1 FROM contact_identifier i WHERE i.contact_id = c.contact_id AND i.type = ?
+				)`, l.typ, l.typ).Error
+			if err != nil {
+				return errors.Wrapf(err, "error while migrating the %s column to identifiers", l.column)
+			}
+		}
+
+		for _, column := range []string{"email_canonical", "email", "phone"} {
+			if !tx.Migrator().HasColumn(&domain.Contact{}, column) {
+				continue
+			}
+			if err := tx.Migrator().DropColumn(&domain.Contact{}, column); err != nil {
+				return errors.Wrapf(err, "error while dropping the %s column", column)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("error while migrating legacy identifiers %s", err)
+	}
 }
@@ -0,0 +1,86 @@
+package sql
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const hasColumnQuery = "SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = CURRENT_SCHEMA() " +
+	"AND table_name = $1 AND column_name = $2"
+
+// TestMigrateLegacyIdentifiers ...
+func TestMigrateLegacyIdentifiers(t *testing.T) {
+	hasColumn := func(mock sqlmock.Sqlmock, column string, has bool) {
+		count := 0
+		if has {
+			count = 1
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(hasColumnQuery)).WithArgs("contact", column).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	}
+
+	tests := []struct {
+		Name   string
+		Expect func(mock sqlmock.Sqlmock)
+	}{
+		{
+			Name: "Legacy columns are copied to identifiers and dropped",
+			Expect: func(mock sqlmock.Sqlmock) {
+				hasColumn(mock, "email_canonical", true)
+				mock.ExpectBegin()
+				hasColumn(mock, "email", true)
+				mock.ExpectExec(regexp.QuoteMeta("COALESCE(c.email_canonical, LOWER(c.email)), c.email")).
+					WithArgs("email", "email").WillReturnResult(sqlmock.NewResult(0, 2))
+				hasColumn(mock, "phone", true)
+				mock.ExpectExec(regexp.QuoteMeta("c.phone, c.phone")).
+					WithArgs("phone", "phone").WillReturnResult(sqlmock.NewResult(0, 2))
+				for _, column := range []string{"email_canonical", "email", "phone"} {
+					hasColumn(mock, column, true)
+					mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE "contact" DROP COLUMN "` + column + `"`)).
+						WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+				mock.ExpectCommit()
+			},
+		},
+		{
+			// an identifier erased after the first start has no legacy column left to be copied back from.
+			Name: "Start after an erasure copies nothing",
+			Expect: func(mock sqlmock.Sqlmock) {
+				hasColumn(mock, "email_canonical", false)
+				mock.ExpectBegin()
+				hasColumn(mock, "email", false)
+				hasColumn(mock, "phone", false)
+				for _, column := range []string{"email_canonical", "email", "phone"} {
+					hasColumn(mock, column, false)
+				}
+				mock.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Silent),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.Expect(mock)
+			migrateLegacyIdentifiers(db)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
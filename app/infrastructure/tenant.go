@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/utils"
+)
+
+const (
+	// HeaderAPIKey is the header carrying the API key a tenant is resolved from.
+	HeaderAPIKey string = "X-API-Key"
+	// HeaderTenantID is the header naming the tenant of a request when no API keys are configured and the header
+	// is allowed.
+	HeaderTenantID string = "X-Tenant-ID"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantMiddleware struct {
+	apiKeys     map[string]string
+	allowHeader bool
+}
+
+// NewTenantMiddleware returns a middleware that stores the tenant of every request in its context under
+// ContextKeyTenantID. When apiKeys, a map of API keys to tenant IDs, is not empty, every request must carry one of
+// the keys in the X-API-Key header. Otherwise requests belong to domain.DefaultTenantID. If allowHeader is set, they
+// may name another tenant in the X-Tenant-ID header; any client can then act on any tenant, so it is meant for local
+// development, and without it requests naming a tenant are rejected.
+func NewTenantMiddleware(apiKeys map[string]string, allowHeader bool) Middleware {
+	return &tenantMiddleware{apiKeys: apiKeys, allowHeader: allowHeader}
+}
+
+// Wrap ...
+func (m *tenantMiddleware) Wrap(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		tenantID, statusCode, msg := m.resolve(r)
+		if statusCode != 0 {
+			utils.ResponseJSON(w, statusCode, utils.NewErrorResponse(statusCode, msg))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithTenantID(r.Context(), tenantID)))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// resolve returns the tenant of the request, or the status code and message it is rejected with.
+func (m *tenantMiddleware) resolve(r *http.Request) (string, int, string) {
+	if len(m.apiKeys) > 0 {
+		tenantID, ok := m.apiKeys[r.Header.Get(HeaderAPIKey)]
+		if !ok {
+			return "", http.StatusUnauthorized, "a valid " + HeaderAPIKey + " header is required"
+		}
+		return tenantID, 0, ""
+	}
+
+	tenantID := r.Header.Get(HeaderTenantID)
+	if tenantID == "" {
+		return domain.DefaultTenantID, 0, ""
+	}
+	if !m.allowHeader {
+		return "", http.StatusForbidden, HeaderTenantID + " is only accepted when tenant.allow_tenant_header is set"
+	}
+	if !ValidTenantID(tenantID) {
+		return "", http.StatusBadRequest, HeaderTenantID + " must be 1 to 64 letters, digits, dashes or underscores"
+	}
+	return tenantID, 0, ""
+}
+
+// ValidTenantID reports whether id can name a tenant.
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+// WithTenantID returns a copy of ctx carrying the tenant.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, ContextKeyTenantID, tenantID)
+}
+
+// TenantID returns the tenant stored in ctx, or domain.DefaultTenantID when there is none.
+func TenantID(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(ContextKeyTenantID).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return domain.DefaultTenantID
+}
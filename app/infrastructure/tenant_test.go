@@ -0,0 +1,80 @@
+package infrastructure_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTenantMiddleware ...
+func TestTenantMiddleware(t *testing.T) {
+	apiKeys := map[string]string{"key-a": "shop-a"}
+	tests := []struct {
+		Name               string
+		APIKeys            map[string]string
+		AllowHeader        bool
+		Header             map[string]string
+		ExpectedStatusCode int
+		ExpectedTenantID   string
+	}{
+		{
+			Name:               "API key resolves its tenant",
+			APIKeys:            apiKeys,
+			Header:             map[string]string{infrastructure.HeaderAPIKey: "key-a"},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTenantID:   "shop-a",
+		},
+		{
+			Name:               "Tenant header is ignored when API keys are configured",
+			APIKeys:            apiKeys,
+			AllowHeader:        true,
+			Header:             map[string]string{infrastructure.HeaderTenantID: "shop-a"},
+			ExpectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			Name:               "Tenant header is refused unless it is allowed",
+			Header:             map[string]string{infrastructure.HeaderTenantID: "shop-a"},
+			ExpectedStatusCode: http.StatusForbidden,
+		},
+		{
+			Name:               "Request without API keys belongs to the default tenant",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTenantID:   "default",
+		},
+		{
+			Name:               "Allowed tenant header names the tenant",
+			AllowHeader:        true,
+			Header:             map[string]string{infrastructure.HeaderTenantID: "shop-b"},
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTenantID:   "shop-b",
+		},
+		{
+			Name:               "Request without the allowed tenant header belongs to the default tenant",
+			AllowHeader:        true,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedTenantID:   "default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var tenantID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenantID = infrastructure.TenantID(r.Context())
+			})
+			req := httptest.NewRequest(http.MethodGet, "/contacts", nil)
+			for k, v := range tt.Header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			infrastructure.NewTenantMiddleware(tt.APIKeys, tt.AllowHeader).Wrap(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.ExpectedStatusCode, rec.Code)
+			assert.Equal(t, tt.ExpectedTenantID, tenantID)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/link-identity/app/domain"
 
@@ -13,24 +14,70 @@ type ContactRepositoryMock struct {
 	mock.Mock
 }
 
-// GetContactByEmail ...
-func (m *ContactRepositoryMock) GetContactByEmail(
+// WithTransaction runs fn with ctx unless an error is configured for the call.
+func (m *ContactRepositoryMock) WithTransaction(
 	ctx context.Context,
-	email string,
-) (*domain.Contact, error) {
-	args := m.Called(ctx, email)
-	return args.Get(0).(*domain.Contact), args.Error(1)
+	fn func(ctx context.Context) error,
+) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+// WithSavepoint runs fn with ctx unless an error is configured for the call.
+func (m *ContactRepositoryMock) WithSavepoint(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+// WithLockedTransaction runs fn with ctx unless an error is configured for the call.
+func (m *ContactRepositoryMock) WithLockedTransaction(
+	ctx context.Context,
+	keys []string,
+	fn func(ctx context.Context) error,
+) error {
+	args := m.Called(ctx, keys)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+// GetContactsByIdentifiers ...
+func (m *ContactRepositoryMock) GetContactsByIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) ([]*domain.Contact, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).([]*domain.Contact), args.Error(1)
 }
 
-// GetContactByPhone ...
-func (m *ContactRepositoryMock) GetContactByPhone(
+// GetContactWithIdentifiers ...
+func (m *ContactRepositoryMock) GetContactWithIdentifiers(
 	ctx context.Context,
-	phone string,
+	keys []domain.IdentifierKey,
 ) (*domain.Contact, error) {
-	args := m.Called(ctx, phone)
+	args := m.Called(ctx, keys)
 	return args.Get(0).(*domain.Contact), args.Error(1)
 }
 
+// GetContactsByLinkedIDs ...
+func (m *ContactRepositoryMock) GetContactsByLinkedIDs(
+	ctx context.Context,
+	ids []uint,
+) ([]*domain.Contact, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*domain.Contact), args.Error(1)
+}
+
 // GetAllContacts ...
 func (m *ContactRepositoryMock) GetAllContacts(
 	ctx context.Context,
@@ -39,6 +86,26 @@ func (m *ContactRepositoryMock) GetAllContacts(
 	return args.Get(0).([]*domain.Contact), args.Error(1)
 }
 
+// ListContacts ...
+func (m *ContactRepositoryMock) ListContacts(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.Contact, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.Contact), args.Error(1)
+}
+
+// ListPrimaryContacts ...
+func (m *ContactRepositoryMock) ListPrimaryContacts(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.Contact, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.Contact), args.Error(1)
+}
+
 // GetAllSecondaryContacts ...
 func (m *ContactRepositoryMock) GetAllSecondaryContacts(
 	ctx context.Context,
@@ -74,3 +141,270 @@ func (m *ContactRepositoryMock) UpdateContact(
 	args := m.Called(ctx, contact)
 	return args.Get(0).(*domain.Contact), args.Error(1)
 }
+
+// VerifyIdentifier ...
+func (m *ContactRepositoryMock) VerifyIdentifier(
+	ctx context.Context,
+	contactID uint,
+	key domain.IdentifierKey,
+	at time.Time,
+) (bool, error) {
+	args := m.Called(ctx, contactID, key, at)
+	return args.Bool(0), args.Error(1)
+}
+
+// UpdateIdentifierValue ...
+func (m *ContactRepositoryMock) UpdateIdentifierValue(
+	ctx context.Context,
+	identifierID uint,
+	value string,
+) error {
+	args := m.Called(ctx, identifierID, value)
+	return args.Error(0)
+}
+
+// CreateContactMerge ...
+func (m *ContactRepositoryMock) CreateContactMerge(
+	ctx context.Context,
+	merge *domain.ContactMerge,
+) error {
+	args := m.Called(ctx, merge)
+	return args.Error(0)
+}
+
+// CreateLinkEvents ...
+func (m *ContactRepositoryMock) CreateLinkEvents(
+	ctx context.Context,
+	events []*domain.LinkEvent,
+) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+// GetLinkEventsByContactIDs ...
+func (m *ContactRepositoryMock) GetLinkEventsByContactIDs(
+	ctx context.Context,
+	ids []uint,
+) ([]*domain.LinkEvent, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*domain.LinkEvent), args.Error(1)
+}
+
+// CreateObservation ...
+func (m *ContactRepositoryMock) CreateObservation(ctx context.Context, observation *domain.Observation) error {
+	args := m.Called(ctx, observation)
+	return args.Error(0)
+}
+
+// GetObservationsByContactIDs ...
+func (m *ContactRepositoryMock) GetObservationsByContactIDs(
+	ctx context.Context,
+	ids []uint,
+) ([]*domain.Observation, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*domain.Observation), args.Error(1)
+}
+
+// DeleteContacts ...
+func (m *ContactRepositoryMock) DeleteContacts(
+	ctx context.Context,
+	ids []uint,
+	hard bool,
+) error {
+	args := m.Called(ctx, ids, hard)
+	return args.Error(0)
+}
+
+// DeleteIdentifiers ...
+func (m *ContactRepositoryMock) DeleteIdentifiers(
+	ctx context.Context,
+	identifierIDs []uint,
+	hard bool,
+) error {
+	args := m.Called(ctx, identifierIDs, hard)
+	return args.Error(0)
+}
+
+// ScrubLinkEvents ...
+func (m *ContactRepositoryMock) ScrubLinkEvents(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+// CreateErasureReceipt ...
+func (m *ContactRepositoryMock) CreateErasureReceipt(
+	ctx context.Context,
+	receipt *domain.ErasureReceipt,
+) error {
+	args := m.Called(ctx, receipt)
+	return args.Error(0)
+}
+
+// CountContactsByIdentifiers ...
+func (m *ContactRepositoryMock) CountContactsByIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) (map[domain.IdentifierKey]int, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(map[domain.IdentifierKey]int), args.Error(1)
+}
+
+// GetFlaggedIdentifiers ...
+func (m *ContactRepositoryMock) GetFlaggedIdentifiers(
+	ctx context.Context,
+	keys []domain.IdentifierKey,
+) ([]*domain.FlaggedIdentifier, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).([]*domain.FlaggedIdentifier), args.Error(1)
+}
+
+// ListFlaggedIdentifiers ...
+func (m *ContactRepositoryMock) ListFlaggedIdentifiers(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.FlaggedIdentifier, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.FlaggedIdentifier), args.Error(1)
+}
+
+// CreateFlaggedIdentifiers ...
+func (m *ContactRepositoryMock) CreateFlaggedIdentifiers(
+	ctx context.Context,
+	flagged []*domain.FlaggedIdentifier,
+) error {
+	args := m.Called(ctx, flagged)
+	return args.Error(0)
+}
+
+// ListDenylistEntries ...
+func (m *ContactRepositoryMock) ListDenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.DenylistEntry), args.Error(1)
+}
+
+// GetDenylistEntry ...
+func (m *ContactRepositoryMock) GetDenylistEntry(ctx context.Context, id uint) (*domain.DenylistEntry, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.DenylistEntry), args.Error(1)
+}
+
+// CreateDenylistEntry ...
+func (m *ContactRepositoryMock) CreateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// UpdateDenylistEntry ...
+func (m *ContactRepositoryMock) UpdateDenylistEntry(ctx context.Context, entry *domain.DenylistEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// DeleteDenylistEntry ...
+func (m *ContactRepositoryMock) DeleteDenylistEntry(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// CreateOutboxEvents ...
+func (m *ContactRepositoryMock) CreateOutboxEvents(ctx context.Context, events []*domain.OutboxEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+// ClaimOutboxEvents ...
+func (m *ContactRepositoryMock) ClaimOutboxEvents(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]*domain.OutboxEvent), args.Error(1)
+}
+
+// MarkOutboxEventsPublished ...
+func (m *ContactRepositoryMock) MarkOutboxEventsPublished(ctx context.Context, ids []uint, at time.Time) error {
+	args := m.Called(ctx, ids, at)
+	return args.Error(0)
+}
+
+// CreateWebhookSubscription ...
+func (m *ContactRepositoryMock) CreateWebhookSubscription(
+	ctx context.Context,
+	subscription *domain.WebhookSubscription,
+) error {
+	args := m.Called(ctx, subscription)
+	return args.Error(0)
+}
+
+// ListWebhookSubscriptions ...
+func (m *ContactRepositoryMock) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.WebhookSubscription), args.Error(1)
+}
+
+// DeleteWebhookSubscription ...
+func (m *ContactRepositoryMock) DeleteWebhookSubscription(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+// CreateWebhookDeliveries ...
+func (m *ContactRepositoryMock) CreateWebhookDeliveries(
+	ctx context.Context,
+	deliveries []*domain.WebhookDelivery,
+) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+// ClaimWebhookDeliveries ...
+func (m *ContactRepositoryMock) ClaimWebhookDeliveries(
+	ctx context.Context,
+	due time.Time,
+	leaseUntil time.Time,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	args := m.Called(ctx, due, leaseUntil, limit)
+	return args.Get(0).([]*domain.WebhookDelivery), args.Error(1)
+}
+
+// UpdateWebhookDelivery ...
+func (m *ContactRepositoryMock) UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+// DeleteWebhookDelivery ...
+func (m *ContactRepositoryMock) DeleteWebhookDelivery(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// CreateWebhookDeadLetter ...
+func (m *ContactRepositoryMock) CreateWebhookDeadLetter(ctx context.Context, letter *domain.WebhookDeadLetter) error {
+	args := m.Called(ctx, letter)
+	return args.Error(0)
+}
+
+// ListWebhookDeadLetters ...
+func (m *ContactRepositoryMock) ListWebhookDeadLetters(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.WebhookDeadLetter, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.WebhookDeadLetter), args.Error(1)
+}
+
+// GetWebhookDeadLetter ...
+func (m *ContactRepositoryMock) GetWebhookDeadLetter(ctx context.Context, id uint) (*domain.WebhookDeadLetter, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.WebhookDeadLetter), args.Error(1)
+}
+
+// MarkWebhookDeadLetterReplayed ...
+func (m *ContactRepositoryMock) MarkWebhookDeadLetterReplayed(ctx context.Context, id uint, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
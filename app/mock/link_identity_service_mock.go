@@ -3,6 +3,7 @@ package mock
 import (
 	"context"
 
+	"github.com/link-identity/app/application"
 	"github.com/link-identity/app/domain"
 
 	"github.com/stretchr/testify/mock"
@@ -14,7 +15,192 @@ type LinkIdentityServiceMock struct {
 }
 
 // Identify ...
-func (m *LinkIdentityServiceMock) Identify(ctx context.Context, email, phone string) ([]*domain.Contact, error) {
-	args := m.Called(ctx, email, phone)
+func (m *LinkIdentityServiceMock) Identify(
+	ctx context.Context,
+	req application.IdentifyRequest,
+) (*application.IdentifyResult, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*application.IdentifyResult), args.Error(1)
+}
+
+// IdentifyBatch ...
+func (m *LinkIdentityServiceMock) IdentifyBatch(
+	ctx context.Context,
+	reqs []application.IdentifyRequest,
+) ([]application.BatchItemResult, error) {
+	args := m.Called(ctx, reqs)
+	return args.Get(0).([]application.BatchItemResult), args.Error(1)
+}
+
+// Backfill ...
+func (m *LinkIdentityServiceMock) Backfill(ctx context.Context) (*application.BackfillReport, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*application.BackfillReport), args.Error(1)
+}
+
+// Merge ...
+func (m *LinkIdentityServiceMock) Merge(
+	ctx context.Context,
+	req application.MergeRequest,
+) (*application.MergeResult, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*application.MergeResult), args.Error(1)
+}
+
+// Split ...
+func (m *LinkIdentityServiceMock) Split(
+	ctx context.Context,
+	req application.SplitRequest,
+) (*application.SplitResult, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*application.SplitResult), args.Error(1)
+}
+
+// LinkEvents ...
+func (m *LinkIdentityServiceMock) LinkEvents(ctx context.Context, contactID uint) ([]*domain.LinkEvent, error) {
+	args := m.Called(ctx, contactID)
+	return args.Get(0).([]*domain.LinkEvent), args.Error(1)
+}
+
+// Observations ...
+func (m *LinkIdentityServiceMock) Observations(ctx context.Context, contactID uint) ([]*domain.Observation, error) {
+	args := m.Called(ctx, contactID)
+	return args.Get(0).([]*domain.Observation), args.Error(1)
+}
+
+// Timeline ...
+func (m *LinkIdentityServiceMock) Timeline(
+	ctx context.Context,
+	contactID uint,
+	offset, limit int,
+) ([]*application.TimelineEntry, error) {
+	args := m.Called(ctx, contactID, offset, limit)
+	return args.Get(0).([]*application.TimelineEntry), args.Error(1)
+}
+
+// GetCluster ...
+func (m *LinkIdentityServiceMock) GetCluster(ctx context.Context, contactID uint) ([]*domain.Contact, error) {
+	args := m.Called(ctx, contactID)
 	return args.Get(0).([]*domain.Contact), args.Error(1)
 }
+
+// FindCluster ...
+func (m *LinkIdentityServiceMock) FindCluster(
+	ctx context.Context,
+	value application.IdentifierValue,
+) ([]*domain.Contact, error) {
+	args := m.Called(ctx, value)
+	return args.Get(0).([]*domain.Contact), args.Error(1)
+}
+
+// VerifyIdentifier ...
+func (m *LinkIdentityServiceMock) VerifyIdentifier(ctx context.Context, req application.VerifyRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+// ExportClusters hands the configured records to fn.
+func (m *LinkIdentityServiceMock) ExportClusters(
+	ctx context.Context,
+	fn func(record *application.ClusterRecord) error,
+) error {
+	args := m.Called(ctx)
+	if records, ok := args.Get(0).([]*application.ClusterRecord); ok {
+		for _, r := range records {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+// Erase ...
+func (m *LinkIdentityServiceMock) Erase(
+	ctx context.Context,
+	req application.ErasureRequest,
+) (*domain.ErasureReceipt, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.ErasureReceipt), args.Error(1)
+}
+
+// FlaggedIdentifiers ...
+func (m *LinkIdentityServiceMock) FlaggedIdentifiers(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.FlaggedIdentifier, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.FlaggedIdentifier), args.Error(1)
+}
+
+// DenylistEntries ...
+func (m *LinkIdentityServiceMock) DenylistEntries(ctx context.Context) ([]*domain.DenylistEntry, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.DenylistEntry), args.Error(1)
+}
+
+// CreateDenylistEntry ...
+func (m *LinkIdentityServiceMock) CreateDenylistEntry(
+	ctx context.Context,
+	req application.DenylistRequest,
+) (*domain.DenylistEntry, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.DenylistEntry), args.Error(1)
+}
+
+// UpdateDenylistEntry ...
+func (m *LinkIdentityServiceMock) UpdateDenylistEntry(
+	ctx context.Context,
+	id uint,
+	req application.DenylistRequest,
+) (*domain.DenylistEntry, error) {
+	args := m.Called(ctx, id, req)
+	return args.Get(0).(*domain.DenylistEntry), args.Error(1)
+}
+
+// DeleteDenylistEntry ...
+func (m *LinkIdentityServiceMock) DeleteDenylistEntry(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// CreateWebhookSubscription ...
+func (m *LinkIdentityServiceMock) CreateWebhookSubscription(
+	ctx context.Context,
+	req application.WebhookSubscriptionRequest,
+) (*domain.WebhookSubscription, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.WebhookSubscription), args.Error(1)
+}
+
+// WebhookSubscriptions ...
+func (m *LinkIdentityServiceMock) WebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.WebhookSubscription), args.Error(1)
+}
+
+// DeleteWebhookSubscription ...
+func (m *LinkIdentityServiceMock) DeleteWebhookSubscription(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// WebhookDeadLetters ...
+func (m *LinkIdentityServiceMock) WebhookDeadLetters(
+	ctx context.Context,
+	afterID uint,
+	limit int,
+) ([]*domain.WebhookDeadLetter, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]*domain.WebhookDeadLetter), args.Error(1)
+}
+
+// ReplayWebhookDeadLetter ...
+func (m *LinkIdentityServiceMock) ReplayWebhookDeadLetter(
+	ctx context.Context,
+	id uint,
+) (*domain.WebhookDelivery, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.WebhookDelivery), args.Error(1)
+}
@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/link-identity/app/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// PublisherMock ...
+type PublisherMock struct {
+	mock.Mock
+}
+
+// Publish ...
+func (m *PublisherMock) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/link-identity/app/domain"
+	"github.com/link-identity/app/infrastructure"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runCommand runs the named maintenance command instead of the http server.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "backfill":
+		return runBackfill(args)
+	case "import":
+		return runImport(args)
+	case "export":
+		return runExport(args)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: backfill, import, export", name)
+	}
+}
+
+// runBackfill re-normalizes the stored identifiers of a tenant and merges the clusters that become equal.
+func runBackfill(args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	tenantID := tenantFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := tenantContext(*tenantID)
+	if err != nil {
+		return err
+	}
+
+	report, err := newLinkIdentityService(newContactRepository()).Backfill(ctx)
+	if report != nil {
+		logEntry.WithFields(logrus.Fields{
+			"scanned":         report.Scanned,
+			"normalized":      report.Normalized,
+			"invalid":         report.Invalid,
+			"merged_clusters": report.MergedClusters,
+		}).Info("Backfill report")
+	}
+	return err
+}
+
+// tenantFlag registers the -tenant flag naming the tenant a command works on.
+func tenantFlag(flags *flag.FlagSet) *string {
+	return flags.String("tenant", domain.DefaultTenantID, "tenant the command works on")
+}
+
+// tenantContext returns a context carrying the tenant.
+func tenantContext(tenantID string) (context.Context, error) {
+	if !infrastructure.ValidTenantID(tenantID) {
+		return nil, fmt.Errorf("invalid tenant %q", tenantID)
+	}
+	return infrastructure.WithTenantID(context.Background(), tenantID), nil
+}
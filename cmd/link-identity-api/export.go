@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/link-identity/app/application"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// runExport writes the consolidated record of every cluster to a file, or to stdout.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", application.ExportFormatNDJSON, "format of the export, ndjson or csv")
+	output := flags.String("output", "", "file the export is written to (default: stdout)")
+	tenantID := tenantFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx, err := tenantContext(*tenantID)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return errors.Wrapf(err, "error while creating %s", *output)
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+
+	writer, err := application.NewClusterWriter(buffered, *format)
+	if err != nil {
+		return err
+	}
+
+	clusters := 0
+	service := newLinkIdentityService(newContactRepository())
+	err = service.ExportClusters(ctx, func(record *application.ClusterRecord) error {
+		clusters++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return errors.Wrap(err, "error while writing the export")
+	}
+
+	// the log would end up in the export on stdout.
+	if *output != "" {
+		logEntry.WithFields(logrus.Fields{"clusters": clusters, "format": *format}).Info("Export finished")
+	}
+	return nil
+}
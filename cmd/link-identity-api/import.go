@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/link-identity/app/application"
+	appconfig "github.com/link-identity/app/config"
+	"github.com/link-identity/app/domain"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// importRecord is a row of an import file.
+type importRecord struct {
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	CreatedAt string `json:"created_at"`
+}
+
+// importRow is a row read from an import file, or the reason it could not be read.
+type importRow struct {
+	number int
+	raw    string
+	record importRecord
+	err    error
+}
+
+// importReader reads the rows of an import file one after the other. It returns io.EOF after the last row.
+type importReader interface {
+	Next() (*importRow, error)
+}
+
+// runImport links the rows of a CSV or NDJSON file of historical orders into the identity graph. Every row keeps
+// its original timestamp, so the oldest contact of a cluster stays its primary whatever order the rows come in.
+//
+// The number of rows done is kept in a checkpoint file after every batch, so an interrupted import continues
+// where it stopped when it is run again. Rows that cannot be read or linked are appended to a rejects file.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "format of the file, csv or ndjson (default: from the file extension)")
+	rejectsPath := flags.String("rejects", "", "file the rejected rows are appended to (default: <file>.rejects)")
+	checkpointPath := flags.String("checkpoint", "", "file the progress is kept in (default: <file>.checkpoint)")
+	batchSize := flags.Int("batch", appconfig.Values.Identity.BatchChunkSize, "rows linked per batch")
+	tenantID := tenantFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: link-identity-api import [flags] <file>")
+	}
+	ctx, err := tenantContext(*tenantID)
+	if err != nil {
+		return err
+	}
+	path := flags.Arg(0)
+	if *rejectsPath == "" {
+		*rejectsPath = path + ".rejects"
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = path + ".checkpoint"
+	}
+	if *batchSize <= 0 {
+		*batchSize = 100
+	}
+
+	return importFile(ctx, newLinkIdentityService(newContactRepository()), path, importOptions{
+		format:         importFormat(*format, path),
+		rejectsPath:    *rejectsPath,
+		checkpointPath: *checkpointPath,
+		batchSize:      *batchSize,
+	})
+}
+
+// importOptions ...
+type importOptions struct {
+	format         string
+	rejectsPath    string
+	checkpointPath string
+	batchSize      int
+}
+
+// importFile links the rows of the file with the service, continuing after the checkpoint when there is one.
+func importFile(
+	ctx context.Context,
+	service application.LinkIdentityService,
+	path string,
+	opts importOptions,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "error while opening %s", path)
+	}
+	defer file.Close()
+
+	reader, err := newImportReader(file, opts.format)
+	if err != nil {
+		return err
+	}
+
+	done, err := readCheckpoint(opts.checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	rejects, err := os.OpenFile(opts.rejectsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "error while opening %s", opts.rejectsPath)
+	}
+	defer rejects.Close()
+
+	imp := &importer{
+		service: service,
+		rejects: json.NewEncoder(rejects),
+		started: time.Now(),
+	}
+
+	var batch []*importRow
+	for {
+		row, err := reader.Next()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if row != nil && row.number > done {
+			batch = append(batch, row)
+		}
+		if len(batch) > 0 && (len(batch) == opts.batchSize || err == io.EOF) {
+			linked, err := imp.link(ctx, batch)
+			if err != nil {
+				return err
+			}
+			// the rejects are only written once the batch is linked, right before the checkpoint moves past it, so
+			// a batch that fails and is run again does not reject its rows twice.
+			if err := imp.record(linked); err != nil {
+				return err
+			}
+			done = batch[len(batch)-1].number
+			if err := writeCheckpoint(opts.checkpointPath, done); err != nil {
+				return err
+			}
+			imp.logProgress(done)
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	imp.logProgress(done)
+	if err := os.Remove(opts.checkpointPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error while removing %s", opts.checkpointPath)
+	}
+	return nil
+}
+
+type importer struct {
+	service  application.LinkIdentityService
+	rejects  *json.Encoder
+	started  time.Time
+	linked   int
+	created  int
+	rejected int
+}
+
+// importBatch is the outcome of a linked batch.
+type importBatch struct {
+	linked   int
+	created  int
+	rejected []importReject
+}
+
+// importReject is a row of a batch that was rejected, and why.
+type importReject struct {
+	row   *importRow
+	cause error
+}
+
+// link links a batch of rows and returns the outcome, including the rows that cannot be read and those the
+// service rejects. Any other failure stops the import before the checkpoint moves past the batch.
+func (imp *importer) link(ctx context.Context, rows []*importRow) (*importBatch, error) {
+	batch := &importBatch{}
+	var reqs []application.IdentifyRequest
+	var pending []*importRow
+	for _, row := range rows {
+		if row.err == nil {
+			var req application.IdentifyRequest
+			req, row.err = row.record.identifyRequest()
+			if row.err == nil {
+				reqs = append(reqs, req)
+				pending = append(pending, row)
+				continue
+			}
+		}
+		batch.rejected = append(batch.rejected, importReject{row: row, cause: row.err})
+	}
+	if len(reqs) == 0 {
+		return batch, nil
+	}
+
+	results, err := imp.service.IdentifyBatch(ctx, reqs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while linking rows %d to %d", rows[0].number, rows[len(rows)-1].number)
+	}
+	for i, result := range results {
+		switch {
+		case result.Err == nil:
+			batch.linked++
+			if result.Result.Created {
+				batch.created++
+			}
+		case isRejected(result.Err):
+			batch.rejected = append(batch.rejected, importReject{row: pending[i], cause: result.Err})
+		default:
+			return nil, errors.Wrapf(result.Err, "error while linking row %d", pending[i].number)
+		}
+	}
+	return batch, nil
+}
+
+// record counts a linked batch and appends its rejected rows to the rejects file.
+func (imp *importer) record(batch *importBatch) error {
+	for _, r := range batch.rejected {
+		if err := imp.reject(r.row, r.cause); err != nil {
+			return err
+		}
+	}
+	imp.linked += batch.linked
+	imp.created += batch.created
+	return nil
+}
+
+func (imp *importer) reject(row *importRow, cause error) error {
+	imp.rejected++
+	err := imp.rejects.Encode(map[string]interface{}{
+		"row":   row.number,
+		"line":  row.raw,
+		"error": cause.Error(),
+	})
+	return errors.Wrapf(err, "error while writing rejected row %d", row.number)
+}
+
+func (imp *importer) logProgress(done int) {
+	logEntry.WithFields(logrus.Fields{
+		"rows_done": done,
+		"linked":    imp.linked,
+		"created":   imp.created,
+		"rejected":  imp.rejected,
+		"elapsed":   time.Since(imp.started).Round(time.Second).String(),
+	}).Info("Import progress")
+}
+
+// isRejected reports whether the service rejected the row itself, rather than failed to link it.
+func isRejected(err error) bool {
+	return errors.Is(err, application.ErrMissingIdentifier) ||
+		errors.Is(err, application.ErrInvalidIdentifier) ||
+		errors.Is(err, application.ErrUnknownIdentifierType)
+}
+
+// identifyRequest returns the request linking the record. created_at is read as RFC 3339, or as a date.
+func (r importRecord) identifyRequest() (application.IdentifyRequest, error) {
+	req := application.IdentifyRequest{Identifiers: []application.IdentifierValue{
+		{Type: domain.IdentifierTypeEmail, Value: r.Email},
+		{Type: domain.IdentifierTypePhone, Value: r.Phone},
+	}}
+
+	createdAt := strings.TrimSpace(r.CreatedAt)
+	if createdAt == "" {
+		return req, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, createdAt); err == nil {
+			req.CreatedAt = &t
+			return req, nil
+		}
+	}
+	return req, errors.Errorf("invalid created_at %q, expected RFC 3339", r.CreatedAt)
+}
+
+func importFormat(format, path string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl", ".json":
+		return "ndjson"
+	default:
+		return "csv"
+	}
+}
+
+func newImportReader(r io.Reader, format string) (importReader, error) {
+	switch format {
+	case "csv":
+		return newCSVImportReader(r)
+	case "ndjson":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	default:
+		return nil, errors.Errorf("unknown import format %q, expected csv or ndjson", format)
+	}
+}
+
+// csvImportReader reads a CSV file with a header row naming the email, phone and optional created_at columns.
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	number  int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "error while reading the csv header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	_, hasEmail := columns["email"]
+	_, hasPhone := columns["phone"]
+	if !hasEmail && !hasPhone {
+		return nil, errors.New("the csv header needs an email or a phone column")
+	}
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+// Next ...
+func (c *csvImportReader) Next() (*importRow, error) {
+	fields, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	c.number++
+	row := &importRow{number: c.number}
+	if err != nil {
+		row.err = err
+		return row, nil
+	}
+
+	row.raw = strings.Join(fields, ",")
+	column := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	row.record = importRecord{Email: column("email"), Phone: column("phone"), CreatedAt: column("created_at")}
+	return row, nil
+}
+
+// ndjsonImportReader reads a file with one JSON object per line. Blank lines are skipped but still counted.
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	number  int
+}
+
+// Next ...
+func (n *ndjsonImportReader) Next() (*importRow, error) {
+	for n.scanner.Scan() {
+		n.number++
+		line := strings.TrimSpace(n.scanner.Text())
+		if line == "" {
+			continue
+		}
+		row := &importRow{number: n.number, raw: line}
+		row.err = json.Unmarshal([]byte(line), &row.record)
+		return row, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error while reading the ndjson file")
+	}
+	return nil, io.EOF
+}
+
+// readCheckpoint returns the number of rows a previous run finished, or 0 when there is no checkpoint.
+func readCheckpoint(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "error while reading %s", path)
+	}
+	done, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid checkpoint in %s", path)
+	}
+	return done, nil
+}
+
+// writeCheckpoint replaces the checkpoint atomically, so an interruption never leaves a partial one behind.
+func writeCheckpoint(path string, done int) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d\n", done)), 0o644); err != nil {
+		return errors.Wrapf(err, "error while writing %s", tmp)
+	}
+	return errors.Wrapf(os.Rename(tmp, path), "error while writing %s", path)
+}
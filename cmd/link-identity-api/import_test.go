@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/link-identity/app/application"
+	mockObject "github.com/link-identity/app/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestImportFile_ResumeAfterFailedBatch ...
+func TestImportFile_ResumeAfterFailedBatch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.csv")
+	content := "email,phone,created_at\n" +
+		"doc@hillvalley.edu,,yesterday\n" +
+		"marty@hillvalley.edu,,2023-04-01\n" +
+		"biff@hillvalley.edu,,last week\n" +
+		"george@hillvalley.edu,,2023-04-02\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := importOptions{
+		format:         "csv",
+		rejectsPath:    filepath.Join(dir, "orders.csv.rejects"),
+		checkpointPath: filepath.Join(dir, "orders.csv.checkpoint"),
+		batchSize:      2,
+	}
+	linked := []application.BatchItemResult{{Result: &application.IdentifyResult{Created: true}}}
+
+	serviceMock := new(mockObject.LinkIdentityServiceMock)
+	serviceMock.On("IdentifyBatch", ctx, mock.Anything).Return(linked, nil).Once()
+	serviceMock.On("IdentifyBatch", ctx, mock.Anything).
+		Return([]application.BatchItemResult(nil), errors.New("connection reset")).Once()
+	serviceMock.On("IdentifyBatch", ctx, mock.Anything).Return(linked, nil).Once()
+
+	// the second batch fails, so the import stops after the first one.
+	err := importFile(ctx, serviceMock, path, opts)
+	assert.Error(t, err)
+	checkpoint, _ := os.ReadFile(opts.checkpointPath)
+	assert.Equal(t, "2\n", string(checkpoint))
+
+	// the rejected row of the failed batch is only written once the batch is linked when the import is resumed.
+	err = importFile(ctx, serviceMock, path, opts)
+	assert.NoError(t, err)
+	rejects, _ := os.ReadFile(opts.rejectsPath)
+	lines := strings.Split(strings.TrimSpace(string(rejects)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], `"row":1`)
+		assert.Contains(t, lines[1], `"row":3`)
+	}
+	assert.NoFileExists(t, opts.checkpointPath)
+	serviceMock.AssertExpectations(t)
+}
@@ -42,19 +42,24 @@ func init() {
 }
 
 func main() {
-	// setup database connection
-	db := sql.NewDBConnection()
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	repo := repository.NewContactRepository(db)
+	repo := newContactRepository()
 
-	identityService := application.NewService(repo)
+	identityService := newLinkIdentityService(repo)
 	identityHandler := httpHandler.NewLinkIdentityHandler(identityService)
+	adminHandler := httpHandler.NewAdminHandler(identityService)
 
 	locationService := application.NewLocationService()
 	locationHandler := httpHandler.NewLocationHandler(locationService)
 
 	// setup the http server
-	router := SetupRouters(identityHandler, locationHandler)
+	router := SetupRouters(identityHandler, adminHandler, locationHandler)
 
 	// identityService address will be changed as port in next PR.
 	srv := &http.Server{
@@ -65,6 +70,18 @@ func main() {
 		Handler:      router}
 	// Server run context
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
+
+	// publish the domain events written to the outbox and deliver them to the webhooks until the server stops
+	publisher := application.Publishers{infrastructure.NewLogPublisher(logEntry), application.NewWebhookPublisher(repo)}
+	dispatcher := application.NewOutboxDispatcher(repo, publisher, appconfig.Values.Outbox)
+	go dispatcher.Run(serverCtx, func(err error) {
+		logEntry.WithError(err).Error("outbox dispatch failed")
+	})
+	deliverer := application.NewWebhookDeliverer(repo, nil, appconfig.Values.Webhook)
+	go deliverer.Run(serverCtx, func(err error) {
+		logEntry.WithError(err).Error("webhook delivery failed")
+	})
+
 	// Listen for syscall signals for process to interrupt/quit
 	sig := make(chan os.Signal, 1)
 
@@ -87,6 +104,9 @@ func main() {
 		}
 		serverStopCtx()
 	}()
+	if len(appconfig.Values.Tenant.APIKeys) == 0 && appconfig.Values.Tenant.AllowTenantHeader {
+		logEntry.Warn("no API keys configured: requests choose their tenant with the X-Tenant-ID header")
+	}
 	// Run the server
 	logEntry.Info("Starting application at port: " + appconfig.Values.Server.Port)
 	errServer := srv.ListenAndServe()
@@ -98,20 +118,80 @@ func main() {
 	logEntry.Info("Application stopped gracefully!")
 }
 
+// newContactRepository connects the contact repository to the database.
+func newContactRepository() repository.ContactRepository {
+	// setup database connection
+	db := sql.NewDBConnection()
+
+	return repository.NewContactRepository(db)
+}
+
+// newLinkIdentityService wires the link identity service to the repository.
+func newLinkIdentityService(repo repository.ContactRepository) application.LinkIdentityService {
+	election, err := application.NewElectionPolicy(appconfig.Values.Identity.PrimaryElection)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return application.NewService(
+		repo,
+		appconfig.Values.Identity,
+		application.WithElectionPolicy(election),
+		application.WithWebhookAllowedHosts(appconfig.Values.Webhook.AllowedHosts),
+	)
+}
+
 // SetupRouters ...
-func SetupRouters(identityHandler *httpHandler.LinkIdentityHandler, locationHandler *httpHandler.LocationHandler) *chi.Mux {
+func SetupRouters(
+	identityHandler *httpHandler.LinkIdentityHandler,
+	adminHandler *httpHandler.AdminHandler,
+	locationHandler *httpHandler.LocationHandler,
+) *chi.Mux {
 	// Base route initialize.
 	router := chi.NewRouter()
+	router.Use(infrastructure.NewRequestIDMiddleware().Wrap)
 	router.Use(infrastructure.NewLoggerMiddleware(logEntry).Wrap)
 
 	//Health check registration
 	router.Get("/health/check", GetHealthCheck)
 	router.Get("/", GetHealthCheck)
 
-	// Register Contact get handler
-	{
-		router.Post("/identify", identityHandler.Identify)
-	}
+	// the identity graph and its administration are kept per tenant
+	router.Group(func(router chi.Router) {
+		router.Use(infrastructure.NewTenantMiddleware(
+			appconfig.Values.Tenant.APIKeys,
+			appconfig.Values.Tenant.AllowTenantHeader,
+		).Wrap)
+
+		// Register Contact get handler
+		{
+			router.Post("/identify", identityHandler.Identify)
+			router.Post("/identify/batch", identityHandler.IdentifyBatch)
+			router.Post("/contacts/merge", identityHandler.Merge)
+			router.Post("/contacts/split", identityHandler.Split)
+			router.Post("/contacts/erase", identityHandler.Erase)
+			router.Get("/contacts", identityHandler.FindContact)
+			router.Get("/contacts/{id}", identityHandler.GetContact)
+			router.Get("/contacts/{id}/events", identityHandler.LinkEvents)
+			router.Get("/contacts/{id}/observations", identityHandler.Observations)
+			router.Get("/contacts/{id}/timeline", identityHandler.Timeline)
+			router.Get("/export", identityHandler.Export)
+			router.Post("/identifiers/verify", identityHandler.VerifyIdentifier)
+		}
+
+		// admin handler
+		{
+			router.Get("/admin/flagged-identifiers", adminHandler.FlaggedIdentifiers)
+			router.Get("/admin/denylist", adminHandler.DenylistEntries)
+			router.Post("/admin/denylist", adminHandler.CreateDenylistEntry)
+			router.Put("/admin/denylist/{id}", adminHandler.UpdateDenylistEntry)
+			router.Delete("/admin/denylist/{id}", adminHandler.DeleteDenylistEntry)
+			router.Get("/admin/webhooks", adminHandler.WebhookSubscriptions)
+			router.Post("/admin/webhooks", adminHandler.CreateWebhookSubscription)
+			router.Delete("/admin/webhooks/{id}", adminHandler.DeleteWebhookSubscription)
+			router.Get("/admin/webhooks/dead-letters", adminHandler.WebhookDeadLetters)
+			router.Post("/admin/webhooks/dead-letters/{id}/replay", adminHandler.ReplayWebhookDeadLetter)
+		}
+	})
 
 	// location handler
 	{